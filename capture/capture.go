@@ -0,0 +1,219 @@
+// Package capture parses captured webhook requests, for verifying them after the fact.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Request is the part of a captured request needed to verify its signature.
+type Request struct {
+	Header http.Header
+	// Body is exactly as it was sent
+	Body []byte
+}
+
+// Load reads a captured request from a file, see Parse for the supported formats.
+func Load(fileName string) (*Request, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	req, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", fileName, err)
+	}
+	return req, nil
+}
+
+// Parse parses a raw HTTP/1.1 request dump, a HAR file or entry, or a JSON
+// document of the form {"headers": {...}, "body": ...}.
+func Parse(data []byte) (*Request, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		var doc map[string]json.RawMessage
+		if err := json.Unmarshal(trimmed, &doc); err != nil {
+			return nil, err
+		}
+		if _, ok := doc["log"]; ok {
+			return parseHAR(doc)
+		}
+		if _, ok := doc["request"]; ok {
+			return parseHAREntry(doc["request"])
+		}
+		return parseDocument(doc)
+	}
+	return parseRaw(data)
+}
+
+// parseRaw parses a raw HTTP/1.1 request, the body is everything after the
+// headers unless Content-Length or chunked encoding say otherwise.
+func parseRaw(data []byte) (*Request, error) {
+	r := bufio.NewReader(bytes.NewReader(data))
+	tp := textproto.NewReader(r)
+	line, err := tp.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("invalid request line: %s", err)
+	}
+	if parts := strings.Fields(line); len(parts) != 3 || !strings.HasPrefix(parts[2], "HTTP/") {
+		return nil, fmt.Errorf("invalid request line %q", line)
+	}
+	mimeHeader, err := tp.ReadMIMEHeader()
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid headers: %s", err)
+	}
+	header := http.Header(mimeHeader)
+
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(header.Get("Transfer-Encoding"), "chunked") {
+		if body, err = io.ReadAll(httputil.NewChunkedReader(bytes.NewReader(body))); err != nil {
+			return nil, fmt.Errorf("invalid chunked body: %s", err)
+		}
+	} else if cl := header.Get("Content-Length"); cl != "" {
+		n, err := strconv.Atoi(cl)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid Content-Length %q", cl)
+		}
+		if n > len(body) {
+			return nil, fmt.Errorf("body is shorter than its Content-Length (%d < %d)", len(body), n)
+		}
+		body = body[:n]
+	}
+	return &Request{Header: header, Body: body}, nil
+}
+
+type harRequest struct {
+	Headers []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"headers"`
+	PostData *struct {
+		Text     string `json:"text"`
+		Encoding string `json:"encoding"`
+	} `json:"postData"`
+}
+
+// parseHAR uses the first entry of the HAR with webhook signature headers, or
+// the first entry if none have them.
+func parseHAR(doc map[string]json.RawMessage) (*Request, error) {
+	var log struct {
+		Entries []struct {
+			Request json.RawMessage `json:"request"`
+		} `json:"entries"`
+	}
+	if err := json.Unmarshal(doc["log"], &log); err != nil {
+		return nil, fmt.Errorf("invalid HAR log: %s", err)
+	}
+	if len(log.Entries) == 0 {
+		return nil, fmt.Errorf("HAR has no entries")
+	}
+	var first *Request
+	for i, entry := range log.Entries {
+		req, err := parseHAREntry(entry.Request)
+		if err != nil {
+			return nil, fmt.Errorf("entry %d: %s", i, err)
+		}
+		if HasSignatureHeaders(req.Header) {
+			return req, nil
+		}
+		if first == nil {
+			first = req
+		}
+	}
+	return first, nil
+}
+
+func parseHAREntry(raw json.RawMessage) (*Request, error) {
+	var har harRequest
+	if err := json.Unmarshal(raw, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR request: %s", err)
+	}
+	header := http.Header{}
+	for _, h := range har.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	var body []byte
+	if har.PostData != nil {
+		body = []byte(har.PostData.Text)
+		if har.PostData.Encoding == "base64" {
+			var err error
+			if body, err = base64.StdEncoding.DecodeString(har.PostData.Text); err != nil {
+				return nil, fmt.Errorf("invalid base64 postData: %s", err)
+			}
+		}
+	}
+	return &Request{Header: header, Body: body}, nil
+}
+
+// parseDocument parses {"headers": {...}, "body": ...}. Header values can be
+// strings or lists of strings, a string body is used as is and any other json
+// body is used exactly as written in the document.
+func parseDocument(doc map[string]json.RawMessage) (*Request, error) {
+	rawHeaders, ok := doc["headers"]
+	if !ok {
+		return nil, fmt.Errorf(`expected a "headers" field`)
+	}
+	var headers map[string]json.RawMessage
+	if err := json.Unmarshal(rawHeaders, &headers); err != nil {
+		return nil, fmt.Errorf("invalid headers: %s", err)
+	}
+	header := http.Header{}
+	for name, raw := range headers {
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			header.Add(name, value)
+			continue
+		}
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, fmt.Errorf("invalid value for header %s", name)
+		}
+		for _, v := range values {
+			header.Add(name, v)
+		}
+	}
+
+	var body []byte
+	if raw, ok := doc["body"]; ok {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			body = []byte(s)
+		} else {
+			body = raw
+		}
+	}
+	return &Request{Header: header, Body: body}, nil
+}
+
+// HasSignatureHeaders reports whether header has the svix-* or webhook-* signature headers.
+func HasSignatureHeaders(header http.Header) bool {
+	id, timestamp, signature := SignatureHeaders(header)
+	return id != "" && timestamp != "" && signature != ""
+}
+
+// SignatureHeaders returns the id, timestamp and signature headers, preferring
+// the svix-* headers and falling back to the webhook-* (Standard Webhooks) ones.
+func SignatureHeaders(header http.Header) (id string, timestamp string, signature string) {
+	id = header.Get("svix-id")
+	timestamp = header.Get("svix-timestamp")
+	signature = header.Get("svix-signature")
+	if id == "" || timestamp == "" || signature == "" {
+		id = header.Get("webhook-id")
+		timestamp = header.Get("webhook-timestamp")
+		signature = header.Get("webhook-signature")
+	}
+	return id, timestamp, signature
+}
@@ -0,0 +1,168 @@
+package capture
+
+import (
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	testMsgID     = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	testTimestamp = "1614265330"
+	testSignature = "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+	testBody      = `{"test": 2432232314}`
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		file        string
+		contentType string
+	}{
+		// Content-Length cuts off the trailing newline
+		{file: "svix.http", contentType: "application/json"},
+		{file: "chunked.http"},
+		// the first entry with signature headers is used
+		{file: "browser.har", contentType: "application/json"},
+		{file: "entry.har"},
+		{file: "document.json"},
+		// a json body is used as written
+		{file: "document-raw-body.json"},
+	}
+	for _, tt := range tests {
+		req, err := Load(filepath.Join("testdata", tt.file))
+		if err != nil {
+			t.Errorf("%s: %s", tt.file, err)
+			continue
+		}
+		if string(req.Body) != testBody {
+			t.Errorf("%s: body = %q, want %q", tt.file, req.Body, testBody)
+		}
+		id, timestamp, signature := SignatureHeaders(req.Header)
+		if id != testMsgID || timestamp != testTimestamp || signature != testSignature {
+			t.Errorf("%s: signature headers = %q, %q, %q", tt.file, id, timestamp, signature)
+		}
+		if got := req.Header.Get("Content-Type"); got != tt.contentType {
+			t.Errorf("%s: Content-Type = %q, want %q", tt.file, got, tt.contentType)
+		}
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join("testdata", "missing.http")); err == nil {
+		t.Error("loaded a missing file, want an error")
+	}
+}
+
+func TestParseRawBody(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+	}{
+		{name: "no Content-Length", raw: "POST / HTTP/1.1\r\nHost: a\r\n\r\nbody\n", want: "body\n"},
+		{name: "Content-Length", raw: "POST / HTTP/1.1\r\nContent-Length: 4\r\n\r\nbody\n", want: "body"},
+		{name: "lf line endings", raw: "POST / HTTP/1.1\nContent-Length: 4\n\nbody", want: "body"},
+		{name: "headers only", raw: "POST / HTTP/1.1\r\nHost: a\r\n", want: ""},
+	}
+	for _, tt := range tests {
+		req, err := Parse([]byte(tt.raw))
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if string(req.Body) != tt.want {
+			t.Errorf("%s: body = %q, want %q", tt.name, req.Body, tt.want)
+		}
+	}
+}
+
+func TestSignatureHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header http.Header
+		want   [3]string
+		has    bool
+	}{
+		{
+			name:   "svix",
+			header: http.Header{"Svix-Id": {"a"}, "Svix-Timestamp": {"1"}, "Svix-Signature": {"v1,x"}},
+			want:   [3]string{"a", "1", "v1,x"},
+			has:    true,
+		},
+		{
+			name:   "standard webhooks",
+			header: http.Header{"Webhook-Id": {"b"}, "Webhook-Timestamp": {"2"}, "Webhook-Signature": {"v1a,y"}},
+			want:   [3]string{"b", "2", "v1a,y"},
+			has:    true,
+		},
+		{
+			name: "svix preferred",
+			header: http.Header{
+				"Svix-Id": {"a"}, "Svix-Timestamp": {"1"}, "Svix-Signature": {"v1,x"},
+				"Webhook-Id": {"b"}, "Webhook-Timestamp": {"2"}, "Webhook-Signature": {"v1a,y"},
+			},
+			want: [3]string{"a", "1", "v1,x"},
+			has:  true,
+		},
+		{
+			name: "incomplete svix headers fall back",
+			header: http.Header{
+				"Svix-Id":    {"a"},
+				"Webhook-Id": {"b"}, "Webhook-Timestamp": {"2"}, "Webhook-Signature": {"v1a,y"},
+			},
+			want: [3]string{"b", "2", "v1a,y"},
+			has:  true,
+		},
+		{
+			name:   "incomplete",
+			header: http.Header{"Svix-Id": {"a"}, "Svix-Timestamp": {"1"}},
+			want:   [3]string{"", "", ""},
+		},
+	}
+	for _, tt := range tests {
+		id, timestamp, signature := SignatureHeaders(tt.header)
+		if got := [3]string{id, timestamp, signature}; got != tt.want {
+			t.Errorf("%s: SignatureHeaders = %q, want %q", tt.name, got, tt.want)
+		}
+		if HasSignatureHeaders(tt.header) != tt.has {
+			t.Errorf("%s: HasSignatureHeaders = %v, want %v", tt.name, !tt.has, tt.has)
+		}
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "empty", data: ""},
+		{name: "not a request", data: "hello world"},
+		{name: "request line without version", data: "POST /webhook\r\n\r\n"},
+		{name: "bad header line", data: "POST / HTTP/1.1\r\nno colon here\r\n\r\n"},
+		{name: "invalid Content-Length", data: "POST / HTTP/1.1\r\nContent-Length: ten\r\n\r\nbody"},
+		{name: "negative Content-Length", data: "POST / HTTP/1.1\r\nContent-Length: -1\r\n\r\nbody"},
+		{name: "short body", data: "POST / HTTP/1.1\r\nContent-Length: 100\r\n\r\nbody"},
+		{name: "bad chunk", data: "POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\nbody\r\n"},
+		{name: "truncated json", data: `{"headers": {"svix-id": "a"`},
+		{name: "document without headers", data: `{"body": "x"}`},
+		{name: "headers not an object", data: `{"headers": ["svix-id"]}`},
+		{name: "header value not a string", data: `{"headers": {"svix-id": 1}}`},
+		{name: "har log not an object", data: `{"log": []}`},
+		{name: "har without entries", data: `{"log": {"entries": []}}`},
+		{name: "har entry without request", data: `{"log": {"entries": [{}]}}`},
+		{name: "har headers not a list", data: `{"request": {"headers": {"a": "b"}}}`},
+		{name: "har bad base64", data: `{"request": {"headers": [], "postData": {"text": "!!", "encoding": "base64"}}}`},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: panicked: %v", tt.name, r)
+				}
+			}()
+			if req, err := Parse([]byte(tt.data)); err == nil {
+				t.Errorf("%s: parsed %+v, want an error", tt.name, req)
+			}
+		}()
+	}
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/inout"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/validators"
+)
+
+type applyCmd struct {
+	cmd *cobra.Command
+}
+
+func newApplyCmd() *applyCmd {
+	fileFlagName := "file"
+	pruneFlagName := "prune"
+
+	ac := &applyCmd{}
+	ac.cmd = &cobra.Command{
+		Use:   "apply",
+		Short: "Reconcile your organization with a desired state file",
+		Long: `apply creates and updates the event types, applications and endpoints of your Svix
+Organization to match a desired state file, see "svix diff --help" for its format.
+
+With --prune, event types and the endpoints of the applications in the file that
+aren't declared in it are deleted (event types are archived). Applications that
+aren't in the file are never deleted.
+
+A change that fails doesn't stop the others from being applied, but the command
+fails once they've all been tried.
+
+Example:
+	svix diff -f svix.yaml --prune
+	svix apply -f svix.yaml --prune`,
+		Args: validators.NoArgs(),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+			svixClient := getSvixClientOrExit()
+
+			fileName, err := cmd.Flags().GetString(fileFlagName)
+			printer.CheckErr(err)
+			prune, err := cmd.Flags().GetBool(pruneFlagName)
+			printer.CheckErr(err)
+
+			state, err := inout.LoadState(fileName)
+			printer.CheckErr(err)
+			ctx := context.Background()
+			changes, err := inout.Plan(ctx, svixClient, state, prune)
+			printer.CheckErr(err)
+
+			if len(changes) == 0 {
+				fmt.Printf("No changes, your organization matches %s.\n", fileName)
+				return
+			}
+			applied, failed := 0, 0
+			for _, change := range changes {
+				printChange(change)
+				if err := change.Apply(ctx, svixClient); err != nil {
+					fmt.Fprintf(os.Stderr, "  Error: %s\n", inout.ErrorDetail(err))
+					failed++
+					continue
+				}
+				applied++
+			}
+			fmt.Printf("\nApplied %d / failed %d changes.\n", applied, failed)
+			if failed > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+	ac.cmd.Flags().StringP(fileFlagName, "f", "svix.yaml", "desired state file")
+	ac.cmd.Flags().Bool(pruneFlagName, false, "delete event types and endpoints not in the file")
+	return ac
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/inout"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/validators"
+)
+
+type diffCmd struct {
+	cmd *cobra.Command
+}
+
+func newDiffCmd() *diffCmd {
+	fileFlagName := "file"
+	pruneFlagName := "prune"
+
+	dc := &diffCmd{}
+	dc.cmd = &cobra.Command{
+		Use:   "diff",
+		Short: "Show the changes svix apply would make to your organization",
+		Long: `diff compares a desired state file with your Svix Organization, and lists the event
+types, applications and endpoints "svix apply" would create (+), update (~) or,
+with --prune, delete (-).
+
+The state file is yaml (or json), for example:
+
+eventTypes:
+  - name: invoice.paid
+    description: An invoice was paid
+    featureFlag: beta
+    schemas:
+      "1": {"type": "object", "properties": {"id": {"type": "string"}}}
+applications:
+  - uid: acme
+    name: Acme Inc.
+    endpoints:
+      - uid: billing
+        url: https://acme.example.com/webhooks
+        filterTypes: [invoice.paid]
+        headers:
+          X-Tenant: acme
+
+Applications are identified by their uid, and an existing application without a uid
+and with the same name is given the uid. Endpoints are identified by their uid or, if
+they don't have one, their url. Endpoint headers are only managed if they are set.
+Event types have no feature flag and aren't archived unless featureFlag or
+"archived: true" are set.
+
+Example:
+	svix diff -f svix.yaml --prune`,
+		Args: validators.NoArgs(),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+			svixClient := getSvixClientOrExit()
+
+			fileName, err := cmd.Flags().GetString(fileFlagName)
+			printer.CheckErr(err)
+			prune, err := cmd.Flags().GetBool(pruneFlagName)
+			printer.CheckErr(err)
+
+			state, err := inout.LoadState(fileName)
+			printer.CheckErr(err)
+			changes, err := inout.Plan(context.Background(), svixClient, state, prune)
+			printer.CheckErr(err)
+
+			if len(changes) == 0 {
+				fmt.Printf("No changes, your organization matches %s.\n", fileName)
+				return
+			}
+			for _, change := range changes {
+				printChange(change)
+			}
+			printChangeSummary(changes)
+		},
+	}
+	dc.cmd.Flags().StringP(fileFlagName, "f", "svix.yaml", "desired state file")
+	dc.cmd.Flags().Bool(pruneFlagName, false, "include deletions of event types and endpoints not in the file")
+	return dc
+}
+
+func printChange(change *inout.Change) {
+	switch change.Action {
+	case inout.ChangeCreate:
+		color.Green("%s", change)
+	case inout.ChangeDelete:
+		color.Red("%s", change)
+	default:
+		color.Yellow("%s", change)
+	}
+}
+
+func printChangeSummary(changes []*inout.Change) {
+	counts := map[string]int{}
+	for _, change := range changes {
+		counts[change.Action]++
+	}
+	fmt.Printf("\n%d to create, %d to update, %d to delete.\n",
+		counts[inout.ChangeCreate], counts[inout.ChangeUpdate], counts[inout.ChangeDelete])
+}
@@ -4,11 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
+	"os"
+	"strings"
 
+	"github.com/araddon/dateparse"
 	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/flags"
 	"github.com/svix/svix-cli/inout"
 	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/validators"
 )
 
 type exportCmd struct {
@@ -16,48 +22,85 @@ type exportCmd struct {
 }
 
 func newExportCmd() *exportCmd {
+	includeSecretsFlagName := "include-secrets"
+	sinceFlagName := "since"
+	untilFlagName := "until"
+	eventTypesFlagName := "event-types"
+	withAttemptsFlagName := "with-attempts"
+	maxRetriesFlagName := "max-retries"
+
+	messagesFileType := "auto"
+
 	cmd := &cobra.Command{
-		Use:   "export event-types",
+		Use:   "export [event-types|messages|all]",
 		Short: "Export data from your Svix Organization",
 	}
 
 	exportEventTypes := &cobra.Command{
 		Use:   "event-types [OUT_FILE]",
 		Short: "Export event-types to a file",
-		Long: `exports event-types from your Svix Organization to a .csv or .json file
+		Long: `exports event-types from your Svix Organization to a .csv, .json, .yaml or .ndjson file
 
 If no OUT_FILE path is supplied it, output to stdout.
 
-CSV Format:
-name,description
+CSV Format (with a header row):
+name,description,archived,featureFlag,schemas
 
 Json Format:
 [{
 	name: "",
-	description: ""
-}]`,
+	description: "",
+	archived: false,
+	featureFlag: "",
+	schemas: {"1": {...json schema}}
+}]
+
+Yaml Format:
+the json format, written as yaml
+
+Ndjson Format:
+an event type per line, in the json format, streamed as they are fetched
+
+Archived event types are included, and schemas are exported as json in csv files.
+The format is inferred from OUT_FILE's extension unless set with --type.`,
 		Args: cobra.RangeArgs(0, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			printer := pretty.NewPrinter(getPrinterOptions(cmd))
 			svixClient := getSvixClientOrExit()
 
-			eventTypes, err := inout.GetAllEventTypes(context.Background(), svixClient)
-			printer.CheckErr(err)
-
-			var outStream io.Writer = printer
 			fileName := ""
 			if len(args) > 0 {
 				fileName = args[0]
+			}
+			fileType := getOrInferFileType(fileName)
+
+			// only json is pretty printed to stdout
+			var outStream io.Writer = os.Stdout
+			if fileType == "json" {
+				outStream = printer
+			}
+			if fileName != "" {
 				outFile, err := inout.CreateOrTruncateFile(fileName)
 				printer.CheckErr(err)
 				defer outFile.Close()
 				outStream = outFile
 			}
-			fileType := getOrInferFileType(fileName)
+
+			if fileType == "ndjson" {
+				err := inout.ExportEventTypesNdjson(context.Background(), svixClient, outStream)
+				printer.CheckErr(err)
+				return
+			}
+
+			eventTypes, err := inout.GetAllEventTypes(context.Background(), svixClient)
+			printer.CheckErr(err)
 			switch fileType {
 			case "csv":
 				err := inout.WriteEventTypesAsCsv(eventTypes, outStream)
 				printer.CheckErr(err)
+			case "yaml":
+				err := inout.WriteEventTypesAsYaml(eventTypes, outStream)
+				printer.CheckErr(err)
 			default:
 				enc := json.NewEncoder(outStream)
 				err := enc.Encode(eventTypes)
@@ -68,6 +111,141 @@ Json Format:
 	exportEventTypes.Flags().AddGoFlag(flag.Lookup(fileTypeFlagName))
 	cmd.AddCommand(exportEventTypes)
 
+	exportAll := &cobra.Command{
+		Use:   "all [OUT_FILE]",
+		Short: "Export your whole organization to an archive file",
+		Long: `exports the event types, applications, endpoints and integrations of your Svix
+Organization to a versioned json archive, which can be imported into another
+environment or a self-hosted server with "svix import all".
+
+Endpoints are exported with their filter types, channels, rate limits and custom
+headers. Sensitive headers can't be read back from the API so aren't exported, and
+signing secrets are only exported with --include-secrets, otherwise imported
+endpoints get new secrets.
+
+If no OUT_FILE path is supplied it, output to stdout.`,
+		Args: cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+			svixClient := getSvixClientOrExit()
+
+			includeSecrets, err := cmd.Flags().GetBool(includeSecretsFlagName)
+			printer.CheckErr(err)
+
+			archive, err := inout.ExportArchive(context.Background(), svixClient, includeSecrets)
+			printer.CheckErr(err)
+
+			var outStream io.Writer = printer
+			if len(args) > 0 {
+				outFile, err := inout.CreateOrTruncateFile(args[0])
+				printer.CheckErr(err)
+				defer outFile.Close()
+				outStream = outFile
+			}
+			printer.CheckErr(inout.WriteArchive(archive, outStream))
+		},
+	}
+	exportAll.Flags().Bool(includeSecretsFlagName, false, "Include endpoint signing secrets in the archive")
+	cmd.AddCommand(exportAll)
+
+	exportMessages := &cobra.Command{
+		Use:   "messages APP_ID [OUT_FILE]",
+		Short: "Export the messages of an application to a file",
+		Long: `exports the messages sent to an application over a time window, with their payloads,
+to an .ndjson or .csv file, so they can be kept beyond Svix's retention period.
+
+If no OUT_FILE path is supplied it, output to stdout.
+
+Ndjson Format:
+a message per line, streamed as they are fetched
+{"id": "", "eventType": "", "eventId": "", "channels": [], "timestamp": "", "payload": {...}, "attempts": [...]}
+
+CSV Format (channels, payload and attempts are json):
+id,eventType,eventId,channels,timestamp,payload,attempts
+
+Messages created after --since and before --until (defaults to now) are exported,
+optionally only those of --event-types. With --with-attempts, the delivery attempts
+of every message are exported along with it. Requests that are rate limited or fail
+with a server error are retried up to --max-retries times.
+
+The format is inferred from OUT_FILE's extension unless set with --type.
+
+Example:
+	svix export messages app_123 messages.ndjson --since 2023-01-01 --until 2023-02-01 --with-attempts`,
+		Args: validators.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			appID := args[0]
+			opts := &inout.MessageExportOptions{}
+			if !cmd.Flags().Changed(sinceFlagName) {
+				printer.CheckErr(fmt.Errorf("--since is required!"))
+			}
+			sinceFlag, err := cmd.Flags().GetString(sinceFlagName)
+			printer.CheckErr(err)
+			since, err := dateparse.ParseAny(sinceFlag)
+			if err != nil {
+				printer.CheckErr(fmt.Errorf("invalid since flag: %s", err))
+			}
+			opts.Since = &since
+			if cmd.Flags().Changed(untilFlagName) {
+				untilFlag, err := cmd.Flags().GetString(untilFlagName)
+				printer.CheckErr(err)
+				until, err := dateparse.ParseAny(untilFlag)
+				if err != nil {
+					printer.CheckErr(fmt.Errorf("invalid until flag: %s", err))
+				}
+				opts.Until = &until
+			}
+			if opts.Until != nil && !opts.Since.Before(*opts.Until) {
+				printer.CheckErr(fmt.Errorf("--since must be before --until!"))
+			}
+			opts.EventTypes, err = cmd.Flags().GetStringArray(eventTypesFlagName)
+			printer.CheckErr(err)
+			opts.WithAttempts, err = cmd.Flags().GetBool(withAttemptsFlagName)
+			printer.CheckErr(err)
+			maxRetries, err := cmd.Flags().GetInt(maxRetriesFlagName)
+			printer.CheckErr(err)
+			svixClient := getRetryingSvixClientOrExit(maxRetries, nil)
+
+			fileName := ""
+			if len(args) > 1 {
+				fileName = args[1]
+			}
+			fileType := messagesFileType
+			if fileType == "auto" {
+				fileType = "ndjson"
+				if strings.HasSuffix(fileName, ".csv") {
+					fileType = "csv"
+				}
+			}
+
+			var outStream io.Writer = os.Stdout
+			if fileName != "" {
+				outFile, err := inout.CreateOrTruncateFile(fileName)
+				printer.CheckErr(err)
+				defer outFile.Close()
+				outStream = outFile
+			}
+
+			var count int
+			if fileType == "csv" {
+				count, err = inout.ExportMessagesCsv(context.Background(), svixClient, appID, opts, outStream)
+			} else {
+				count, err = inout.ExportMessagesNdjson(context.Background(), svixClient, appID, opts, outStream)
+			}
+			printer.CheckErr(err)
+			fmt.Fprintf(os.Stderr, "Exported %d messages.\n", count)
+		},
+	}
+	exportMessages.Flags().String(sinceFlagName, "", "export messages created after this time (required)")
+	exportMessages.Flags().String(untilFlagName, "", "export messages created before this time (defaults to now)")
+	exportMessages.Flags().StringArray(eventTypesFlagName, []string{}, "only export messages of these event types")
+	exportMessages.Flags().Bool(withAttemptsFlagName, false, "export the delivery attempts of every message")
+	exportMessages.Flags().Int(maxRetriesFlagName, inout.DefaultMaxRetries, "number of times a rate limited or failed request is retried")
+	exportMessages.Flags().Var(flags.NewEnum(&messagesFileType, "auto", "ndjson", "csv"), fileTypeFlagName, "auto|ndjson|csv")
+	cmd.AddCommand(exportMessages)
+
 	return &exportCmd{
 		cmd: cmd,
 	}
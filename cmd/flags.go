@@ -15,10 +15,10 @@ import (
 
 var fileTypeFlagName = "type"
 var fileTypeFlagValue string = "auto"
-var fileTypeFlag = flags.NewEnum(&fileTypeFlagValue, "auto", "json", "csv")
+var fileTypeFlag = flags.NewEnum(&fileTypeFlagValue, "auto", "json", "csv", "yaml", "ndjson")
 
 func init() {
-	flag.Var(fileTypeFlag, fileTypeFlagName, "auto|json|csv")
+	flag.Var(fileTypeFlag, fileTypeFlagName, "auto|json|csv|yaml|ndjson")
 }
 
 func getOrInferFileType(fileName string) string {
@@ -27,6 +27,10 @@ func getOrInferFileType(fileName string) string {
 		switch {
 		case strings.HasSuffix(fileName, ".csv"):
 			fileType = "csv"
+		case strings.HasSuffix(fileName, ".yaml"), strings.HasSuffix(fileName, ".yml"):
+			fileType = "yaml"
+		case strings.HasSuffix(fileName, ".ndjson"), strings.HasSuffix(fileName, ".jsonl"):
+			fileType = "ndjson"
 		default:
 			fileType = "json"
 		}
@@ -6,11 +6,16 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/flags"
 	"github.com/svix/svix-cli/inout"
 	"github.com/svix/svix-cli/pretty"
 	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
 )
 
 type importCmd struct {
@@ -19,40 +24,198 @@ type importCmd struct {
 
 func newImportCmd() *importCmd {
 	forceFlagName := "force"
+	dryRunFlagName := "dry-run"
+	reportFlagName := "report"
+	fromFlagName := "from"
+	parallelismFlagName := "parallelism"
+	maxRetriesFlagName := "max-retries"
+
+	reportFormat := "table"
+	source := "events"
 
 	cmd := &cobra.Command{
-		Use:   "import event-types",
+		Use:   "import [event-types|all]",
 		Short: "Import data to your Svix Organization",
 	}
 
 	importEventTypes := &cobra.Command{
-		Use:   "event-types [IN_FILE]",
+		Use:   "event-types [IN_FILE|DIR]",
 		Short: "Import event-types from a file",
-		Long: `imports event-types into your Svix Organization from json or csv
+		Long: `imports event-types into your Svix Organization from json, csv, yaml or ndjson
 
 If no IN_FILE path is supplied it, we will read from stdin.
 
-CSV Format:
-name,description
+CSV Format (the archived, featureFlag and schemas columns and the header row are optional):
+name,description,archived,featureFlag,schemas
 
 Json Format:
 [{
 	name: "",
-	description: ""
-}]`,
+	description: "",
+	archived: false,
+	featureFlag: "",
+	schemas: {"1": {...json schema}}
+}]
+
+Yaml Format:
+the json format, written as yaml
+
+Ndjson Format:
+an event type per line, in the json format
+
+The format is inferred from IN_FILE's extension unless set with --type.
+
+Event types can also be converted from other formats with --from:
+  openapi       an OpenAPI 3 document (yaml or json), each of its webhooks becomes an
+                event type with the schema of its request body, or if it has no
+                webhooks, each of its components.schemas
+  json-schema   a directory of JSON Schema files, each becomes an event type named
+                after the file, e.g. invoice.paid.json is invoice.paid
+
+A failure to import an event type doesn't stop the import. Every event type's outcome
+(created, updated, skipped, unchanged or failed) is reported as a table or, with
+--report json, as json, and the command fails if any event type failed to import.
+With --dry-run, the file is only compared with the existing event types, and the
+report shows what an import would do.
+
+Event types are imported --parallelism at a time. Requests that are rate limited or
+fail with a server error are retried up to --max-retries times, waiting as long as
+the Retry-After header asks or backing off exponentially. When stderr is a terminal,
+the import's progress is shown there.`,
 		Args: cobra.RangeArgs(0, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			printer := pretty.NewPrinter(getPrinterOptions(cmd))
-			svixClient := getSvixClientOrExit()
 
 			force, err := cmd.Flags().GetBool(forceFlagName)
 			printer.CheckErr(err)
+			dryRun, err := cmd.Flags().GetBool(dryRunFlagName)
+			printer.CheckErr(err)
+			parallelism, err := cmd.Flags().GetInt(parallelismFlagName)
+			printer.CheckErr(err)
+			if parallelism < 1 {
+				printer.CheckErr("--parallelism must be at least 1!")
+			}
+			maxRetries, err := cmd.Flags().GetInt(maxRetriesFlagName)
+			printer.CheckErr(err)
+
+			var retries int64
+			svixClient := getRetryingSvixClientOrExit(maxRetries, func(wait time.Duration) {
+				atomic.AddInt64(&retries, 1)
+			})
+
+			var eventTypes []*svix.EventTypeIn
+			if source == "json-schema" {
+				if len(args) == 0 {
+					printer.CheckErr("A directory of JSON Schema files is required with --from json-schema!")
+				}
+				eventTypes, err = inout.EventTypesFromJsonSchemaDir(args[0])
+				printer.CheckErr(err)
+			} else {
+				var reader io.Reader
+				fileName := ""
+				if len(args) > 0 {
+					fileName = args[0]
+					file, err := os.Open(fileName)
+					printer.CheckErr(err)
+					defer file.Close()
+					reader = file
+				} else {
+					isReadable, err := utils.IsStdinReadable()
+					printer.CheckErr(err)
+					if !isReadable {
+						printer.CheckErr(fmt.Errorf("stdin not readable"))
+					}
+					reader = os.Stdin
+				}
+
+				switch {
+				case source == "openapi":
+					eventTypes, err = inout.EventTypesFromOpenApi(reader)
+				case getOrInferFileType(fileName) == "csv":
+					eventTypes, err = inout.ReadEventTypesCsv(reader)
+				case getOrInferFileType(fileName) == "yaml":
+					eventTypes, err = inout.ReadEventTypesYaml(reader)
+				case getOrInferFileType(fileName) == "ndjson":
+					eventTypes, err = inout.ReadEventTypesNdjson(reader)
+				default:
+					eventTypes, err = inout.ReadEventTypesJson(reader)
+				}
+				printer.CheckErr(err)
+			}
+
+			opts := &inout.ImportOptions{
+				Update:      force,
+				DryRun:      dryRun,
+				Parallelism: parallelism,
+			}
+			isTTY, _, _ := utils.IsTTY(os.Stderr)
+			var bar *pretty.ProgressBar
+			if isTTY && !dryRun {
+				bar = pretty.NewProgressBar(os.Stderr, "Importing event types")
+				opts.Progress = func(done int, total int) {
+					bar.Set(done, total, fmt.Sprintf("(%d retries)", atomic.LoadInt64(&retries)))
+				}
+			}
+			report, err := inout.ImportEventTypes(context.Background(), svixClient, eventTypes, opts)
+			if bar != nil {
+				bar.Finish()
+			}
+			printer.CheckErr(err)
+			if reportFormat == "json" {
+				printer.Print(report)
+			} else {
+				printImportReport(os.Stdout, report)
+			}
+			if report.Failed() {
+				os.Exit(1)
+			}
+		},
+	}
+	importEventTypes.Flags().AddGoFlag(flag.Lookup(fileTypeFlagName))
+	importEventTypes.Flags().Bool(forceFlagName, false, "Update event type if already exists (defaults to skipping)")
+	importEventTypes.Flags().Bool(dryRunFlagName, false, "Only report what would be imported, without changing anything")
+	importEventTypes.Flags().Var(flags.NewEnum(&reportFormat, "table", "json"), reportFlagName, "table|json")
+	importEventTypes.Flags().Var(flags.NewEnum(&source, "events", "openapi", "json-schema"), fromFlagName, "events|openapi|json-schema")
+	importEventTypes.Flags().Int(parallelismFlagName, 4, "Number of event types imported at once")
+	importEventTypes.Flags().Int(maxRetriesFlagName, inout.DefaultMaxRetries, "Number of times a rate limited or failed request is retried")
+	cmd.AddCommand(importEventTypes)
+
+	importAll := &cobra.Command{
+		Use:   "all [IN_FILE]",
+		Short: "Import an archive of a whole organization",
+		Long: `imports an archive made by "svix export all" into your Svix Organization, creating
+its event types, applications, endpoints and integrations.
+
+Applications and endpoints are matched with existing ones, which are skipped unless
+--force is given, in which case they are updated. Applications are matched by uid, or
+by name if they have none, and endpoints by uid, or by url if they have none. So an
+archive can be imported again without duplicating anything. Integrations are skipped
+if the application already has one with the same name. Everything else is created,
+with new IDs. With --force, endpoints exported with their secret (--include-secrets)
+have their secret rotated to the exported one, if it differs.
+
+A failure to import an item doesn't stop the import, though the endpoints and
+integrations of an application that failed are left out. Every item's outcome
+(created, updated, skipped or failed) is reported as a table or, with --report json,
+as json, and the command fails if any item failed to import.
+
+Requests that are rate limited or fail with a server error are retried up to
+--max-retries times.
+
+If no IN_FILE path is supplied it, we will read from stdin.`,
+		Args: cobra.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			force, err := cmd.Flags().GetBool(forceFlagName)
+			printer.CheckErr(err)
+			maxRetries, err := cmd.Flags().GetInt(maxRetriesFlagName)
+			printer.CheckErr(err)
+			svixClient := getRetryingSvixClientOrExit(maxRetries, nil)
 
 			var reader io.Reader
-			fileName := ""
 			if len(args) > 0 {
-				fileName = args[0]
-				file, err := os.Open(fileName)
+				file, err := os.Open(args[0])
 				printer.CheckErr(err)
 				defer file.Close()
 				reader = file
@@ -65,22 +228,42 @@ Json Format:
 				reader = os.Stdin
 			}
 
-			fileType := getOrInferFileType(fileName)
-			switch fileType {
-			case "csv":
-				err := inout.ImportEventTypesCsv(context.Background(), svixClient, reader, force)
-				printer.CheckErr(err)
-			default:
-				err := inout.ImportEventTypesJson(context.Background(), svixClient, reader, force)
-				printer.CheckErr(err)
+			archive, err := inout.ReadArchive(reader)
+			printer.CheckErr(err)
+			report, err := inout.ImportArchive(context.Background(), svixClient, archive, force)
+			printer.CheckErr(err)
+			if reportFormat == "json" {
+				printer.Print(report)
+			} else {
+				printImportReport(os.Stdout, report)
+			}
+			if report.Failed() {
+				os.Exit(1)
 			}
 		},
 	}
-	importEventTypes.Flags().AddGoFlag(flag.Lookup(fileTypeFlagName))
-	importEventTypes.Flags().Bool(forceFlagName, false, "Update event type if already exists (defaults to skipping)")
-	cmd.AddCommand(importEventTypes)
+	importAll.Flags().Bool(forceFlagName, false, "Update applications and endpoints if they already exist (defaults to skipping)")
+	importAll.Flags().Var(flags.NewEnum(&reportFormat, "table", "json"), reportFlagName, "table|json")
+	importAll.Flags().Int(maxRetriesFlagName, inout.DefaultMaxRetries, "Number of times a rate limited or failed request is retried")
+	cmd.AddCommand(importAll)
 
 	return &importCmd{
 		cmd: cmd,
 	}
 }
+
+func printImportReport(w io.Writer, report *inout.ImportReport) {
+	if report.DryRun {
+		fmt.Fprintf(w, "Dry run, nothing was imported.\n\n")
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tOUTCOME\tERROR")
+	for _, result := range report.Results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", result.Name, result.Outcome, result.Error)
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "\n%d created, %d updated, %d skipped, %d unchanged, %d failed\n",
+		report.Counts[inout.OutcomeCreated], report.Counts[inout.OutcomeUpdated], report.Counts[inout.OutcomeSkipped],
+		report.Counts[inout.OutcomeUnchanged], report.Counts[inout.OutcomeFailed])
+}
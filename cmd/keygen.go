@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/flags"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/signature"
+	"github.com/svix/svix-cli/validators"
+)
+
+type keygenCmd struct {
+	cmd *cobra.Command
+}
+
+func newKeygenCmd() *keygenCmd {
+	typeFlagName := "type"
+
+	keyType := "ed25519"
+
+	kc := &keygenCmd{}
+	kc.cmd = &cobra.Command{
+		Use:   "keygen",
+		Short: "Generate webhook signing keys",
+		Long: `keygen generates an ed25519 key pair for asymmetric (v1a) webhook signatures, as
+used by the Standard Webhooks spec. The whsk_ secret key signs messages and the whpk_
+public key verifies them, so it can be shared with receivers.
+
+With --type symmetric, a whsec_ secret for v1 signatures is generated instead.
+
+Example:
+	svix keygen
+	svix sign --secret whsk_... '{"type":"invoice.paid"}'
+	svix verify --secret whpk_... --request webhook.http`,
+		Args: validators.NoArgs(),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			if keyType == "symmetric" {
+				secret, err := signature.GenerateSecret()
+				printer.CheckErr(err)
+				printer.Print(map[string]string{
+					"secret": secret,
+				})
+				return
+			}
+
+			publicKey, secretKey, err := signature.GenerateKeyPair()
+			printer.CheckErr(err)
+			printer.Print(map[string]string{
+				"publicKey": publicKey,
+				"secretKey": secretKey,
+			})
+		},
+	}
+	kc.cmd.Flags().Var(flags.NewEnum(&keyType, "ed25519", "symmetric"), typeFlagName, "ed25519|symmetric")
+	return kc
+}
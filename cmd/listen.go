@@ -3,13 +3,18 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/url"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"github.com/svix/svix-cli/config"
 	"github.com/svix/svix-cli/pretty"
 	"github.com/svix/svix-cli/relay"
+	"github.com/svix/svix-cli/signature"
+	"github.com/svix/svix-cli/tui"
 )
 
 type listenCmd struct {
@@ -18,9 +23,22 @@ type listenCmd struct {
 
 func newListenCmd() *listenCmd {
 	noLoggingFlagName := "no-logging"
+	localFlagName := "local"
+	routeFlagName := "route"
+	recordFlagName := "record"
+	verifySecretFlagName := "verify-secret"
+	rejectUnverifiedFlagName := "reject-unverified"
+	tuiFlagName := "tui"
+	transformFlagName := "transform"
+	drainTimeoutFlagName := "drain-timeout"
+	localTimeoutFlagName := "local-timeout"
+	maxConcurrencyFlagName := "max-concurrency"
+	serialFlagName := "serial"
+	rateLimitFlagName := "rate-limit"
+	rateBurstFlagName := "rate-burst"
 	lc := &listenCmd{}
 	lc.cmd = &cobra.Command{
-		Use:   `listen localURL (ex. http://localhost:8000/webhook/)`,
+		Use:   `listen [localURL] (ex. http://localhost:8000/webhook/)`,
 		Short: "Forward webhook requests a local url",
 		Long: `listen creates an on-the-fly publicly accessible URL for use when testing webhooks.
 
@@ -32,15 +50,101 @@ Example:
 	svix listen http://localhost:8000/webhook/
 
 The above command will return you a unique URL and forward any POST requests it receives
-to http://localhost:8000/webhook/`,
-		Args: cobra.ExactArgs(1),
+to http://localhost:8000/webhook/
+
+To relay through a server started with "svix relay serve" instead of the public relay:
+	svix listen --local http://localhost:8000/webhook/
+
+Requests can also be routed to several local URLs with --route MATCH=URL[,URL...],
+where MATCH is one of:
+	default             requests no other route matched (same as the localURL argument)
+	path:GLOB           the path requested after the relay token (--local only)
+	header:NAME:GLOB    the value of the request header NAME
+	GLOB                the event type, read from the "type" field of the json body
+
+Routes are checked in order. When a route has several URLs the request is sent to all
+of them, and the response of the first one is returned to the webhook sender. With
+--local, the path requested after the relay token is appended to the local URLs.
+
+Example:
+	svix listen --route 'invoice.*=http://localhost:8001/hooks' --route 'default=http://localhost:8000/'
+
+Use --record FILE to append every request and the local response to a JSONL file,
+recorded requests can be sent again with "svix replay".
+
+Use --verify-secret to check the signature of every request with your endpoint's signing
+secret (whsec_) or public key (whpk_), from the svix-* or webhook-* headers as with
+"svix verify". Add --reject-unverified to respond with a 401 instead of forwarding
+requests that fail verification.
+
+Use --transform FILE to rewrite requests before they're forwarded to the local server,
+and responses before they're returned to the webhook sender. FILE is a json file of rules,
+rules without a "match" (using the same syntax as routes) apply to every request:
+	{
+	  "request": [
+	    {"match": "invoice.*", "method": "PUT", "path": "/v2/invoices",
+	     "setHeaders": {"X-Api-Version": "2"}, "removeHeaders": ["svix-signature"],
+	     "setBodyFields": {"data.source": "svix"}, "removeBodyFields": ["timestamp"]}
+	  ],
+	  "response": [
+	    {"status": 200, "setHeaders": {"X-Relayed": "true"},
+	     "replaceBody": [{"old": "error", "new": "ok"}]}
+	  ]
+	}
+Request rules may also set "body" and "replaceBody", response rules "removeHeaders",
+"body", "setBodyFields" and "removeBodyFields". Recorded requests are stored untransformed.
+
+When the local server can't be reached the cli responds to the webhook sender itself,
+with a "502 Bad Gateway" (or "504 Gateway Timeout" after --local-timeout) and the
+Svix-Cli-Generated header set.
+
+By default requests are forwarded as soon as they're received. Use --max-concurrency N
+to limit how many are forwarded at once, --serial to forward them one at a time in the
+order they were received, and --rate-limit N (with --rate-burst) to start at most N per
+second. Copies of a request sent to the other targets of a route count towards
+--max-concurrency too. Requests waiting for their turn are queued, and the queue size
+is logged.
+
+On Ctrl-C the cli stops accepting requests, waits up to --drain-timeout for requests
+already being forwarded to complete and prints a summary of the session.
+
+Use --tui for a full screen view of relayed requests, showing the headers and body of
+the selected request and allowing it to be replayed to the local server.`,
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			printer := pretty.NewPrinter(getPrinterOptions(cmd))
 
-			urlStr := args[0]
-			url, err := url.Parse(urlStr)
-			if err != nil {
-				return fmt.Errorf("invalid local url %s", urlStr)
+			local, err := cmd.Flags().GetBool(localFlagName)
+			printer.CheckErr(err)
+			rawRoutes, err := cmd.Flags().GetStringArray(routeFlagName)
+			printer.CheckErr(err)
+			var routes []*relay.Route
+			hasDefaultRoute := false
+			for _, rawRoute := range rawRoutes {
+				route, err := relay.ParseRoute(rawRoute)
+				if err != nil {
+					return err
+				}
+				// the public relay doesn't send the path requested after the token
+				if route.IsPath() && !local {
+					return fmt.Errorf("path routes need --local, the path is only relayed by \"svix relay serve\"")
+				}
+				hasDefaultRoute = hasDefaultRoute || route.IsDefault()
+				routes = append(routes, route)
+			}
+
+			var localURL *url.URL
+			if len(args) > 0 {
+				if hasDefaultRoute {
+					return fmt.Errorf("a localURL can't be combined with a default route")
+				}
+				urlStr := args[0]
+				localURL, err = url.Parse(urlStr)
+				if err != nil {
+					return fmt.Errorf("invalid local url %s", urlStr)
+				}
+			} else if len(routes) == 0 {
+				return fmt.Errorf("a localURL or at least one --route is required")
 			}
 			var token string
 			if viper.IsSet("relay_token") {
@@ -55,15 +159,140 @@ to http://localhost:8000/webhook/`,
 			noLogging, err := cmd.Flags().GetBool(noLoggingFlagName)
 			printer.CheckErr(err)
 
-			client := relay.NewClient(token, url, &relay.ClientOptions{
+			drainTimeout, err := cmd.Flags().GetDuration(drainTimeoutFlagName)
+			printer.CheckErr(err)
+			localTimeout, err := cmd.Flags().GetDuration(localTimeoutFlagName)
+			printer.CheckErr(err)
+			maxConcurrency, err := cmd.Flags().GetInt(maxConcurrencyFlagName)
+			printer.CheckErr(err)
+			serial, err := cmd.Flags().GetBool(serialFlagName)
+			printer.CheckErr(err)
+			rateLimit, err := cmd.Flags().GetFloat64(rateLimitFlagName)
+			printer.CheckErr(err)
+			rateBurst, err := cmd.Flags().GetInt(rateBurstFlagName)
+			printer.CheckErr(err)
+			if maxConcurrency < 0 || rateLimit < 0 || rateBurst < 1 {
+				return fmt.Errorf("--%s and --%s can't be negative, and --%s must be at least 1", maxConcurrencyFlagName, rateLimitFlagName, rateBurstFlagName)
+			}
+			if serial && cmd.Flags().Changed(maxConcurrencyFlagName) {
+				return fmt.Errorf("--%s can't be combined with --%s", serialFlagName, maxConcurrencyFlagName)
+			}
+
+			opts := &relay.ClientOptions{
 				DisableSecurity: viper.GetBool("relay_disable_security"),
 				RelayDebugUrl:   viper.GetString("relay_debug_url"),
 				Logging:         !noLogging,
-			})
-			client.Listen(context.Background())
+				Routes:          routes,
+				LocalTimeout:    localTimeout,
+				DrainTimeout:    drainTimeout,
+				MaxConcurrency:  maxConcurrency,
+				Serial:          serial,
+				RateLimit:       rateLimit,
+				RateBurst:       rateBurst,
+			}
+			if local {
+				// the local relay server has no log viewer and doesn't use tls
+				if opts.RelayDebugUrl == "" {
+					opts.RelayDebugUrl = relay.DefaultServerAddr
+				}
+				opts.DisableSecurity = true
+				opts.Logging = false
+			}
+
+			if cmd.Flags().Changed(recordFlagName) {
+				recordFile, err := cmd.Flags().GetString(recordFlagName)
+				printer.CheckErr(err)
+				recorder, err := relay.NewRecorder(recordFile)
+				printer.CheckErr(err)
+				defer recorder.Close()
+				opts.Recorder = recorder
+			}
+
+			if cmd.Flags().Changed(verifySecretFlagName) {
+				secret, err := cmd.Flags().GetString(verifySecretFlagName)
+				printer.CheckErr(err)
+				key, err := signature.ParseKey(secret)
+				if err != nil {
+					printer.CheckErr(fmt.Errorf("Failed to parse signing secret: %s", err.Error()))
+				}
+				opts.VerifyKey = key
+			}
+			rejectUnverified, err := cmd.Flags().GetBool(rejectUnverifiedFlagName)
+			printer.CheckErr(err)
+			if rejectUnverified && opts.VerifyKey == nil {
+				return fmt.Errorf("--%s requires --%s", rejectUnverifiedFlagName, verifySecretFlagName)
+			}
+			opts.RejectUnverified = rejectUnverified
+
+			if cmd.Flags().Changed(transformFlagName) {
+				transformFile, err := cmd.Flags().GetString(transformFlagName)
+				printer.CheckErr(err)
+				transformations, err := relay.LoadTransformations(transformFile)
+				printer.CheckErr(err)
+				opts.Transformations = transformations
+			}
+
+			ctx, stop := interruptContext()
+			defer stop()
+
+			useTUI, err := cmd.Flags().GetBool(tuiFlagName)
+			printer.CheckErr(err)
+			if useTUI {
+				inspector := tui.NewInspector(printer)
+				opts.OnRecord = inspector.Add
+				opts.OnConnect = inspector.SetReceiveURL
+				// relay output is shown in the inspector's log pane
+				output, noColor := color.Output, color.NoColor
+				color.Output = inspector
+				color.NoColor = true
+
+				client := relay.NewClient(token, localURL, opts)
+				ctx, cancel := context.WithCancel(ctx)
+				done := make(chan struct{})
+				go func() {
+					client.Listen(ctx)
+					close(done)
+				}()
+				err := inspector.Run(client.Replay)
+				cancel()
+				<-done
+
+				color.Output, color.NoColor = output, noColor
+				printSessionSummary(color.Output, client.Stats())
+				return err
+			}
+
+			client := relay.NewClient(token, localURL, opts)
+			client.Listen(ctx)
+			printSessionSummary(color.Output, client.Stats())
 			return nil
 		},
 	}
 	lc.cmd.Flags().Bool(noLoggingFlagName, false, "Disables History Logging")
+	lc.cmd.Flags().StringArray(routeFlagName, []string{}, "route requests to a local url, MATCH=URL[,URL...] (repeatable)")
+	lc.cmd.Flags().String(recordFlagName, "", "append relayed requests and responses to a JSONL file")
+	lc.cmd.Flags().String(verifySecretFlagName, "", "verify the signature of relayed requests with this signing secret or public key")
+	lc.cmd.Flags().Bool(rejectUnverifiedFlagName, false, "respond with a 401 instead of forwarding requests failing verification")
+	lc.cmd.Flags().String(transformFlagName, "", "rewrite requests and responses with the rules in a json file")
+	lc.cmd.Flags().Bool(tuiFlagName, false, "show relayed requests in an interactive full screen inspector")
+	lc.cmd.Flags().Duration(localTimeoutFlagName, 30*time.Second, "how long to wait for the local server to respond")
+	lc.cmd.Flags().Int(maxConcurrencyFlagName, 0, "maximum number of requests forwarded at once (0 is unlimited)")
+	lc.cmd.Flags().Bool(serialFlagName, false, "forward requests one at a time, in the order they were received")
+	lc.cmd.Flags().Float64(rateLimitFlagName, 0, "maximum number of requests started per second (0 is unlimited)")
+	lc.cmd.Flags().Int(rateBurstFlagName, 1, "number of requests that may be started at once under --rate-limit")
+	lc.cmd.Flags().Duration(drainTimeoutFlagName, 10*time.Second, "how long to wait for in-flight requests when shutting down")
+	lc.cmd.Flags().Bool(localFlagName, false, "Connect to a local relay started with 'svix relay serve' (uses relay_debug_url if set)")
 	return lc
 }
+
+func printSessionSummary(w io.Writer, stats relay.Stats) {
+	fmt.Fprintf(w, "\nSession summary:\n")
+	fmt.Fprintf(w, "  Requests forwarded: %d\n", stats.Forwarded)
+	fmt.Fprintf(w, "  Failures:           %d\n", stats.Failed)
+	if stats.Rejected > 0 {
+		fmt.Fprintf(w, "  Rejected:           %d\n", stats.Rejected)
+	}
+	if stats.Forwarded > 0 {
+		fmt.Fprintf(w, "  Average latency:    %dms\n", stats.AverageLatency().Milliseconds())
+	}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/mock"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/validators"
+)
+
+type mockServerCmd struct {
+	cmd *cobra.Command
+}
+
+func newMockServerCmd() *mockServerCmd {
+	addrFlagName := "addr"
+	fixturesFlagName := "fixtures"
+	retryScheduleFlagName := "retry-schedule"
+
+	msc := &mockServerCmd{}
+	msc.cmd = &cobra.Command{
+		Use:   "mock-server",
+		Short: "Run an in-memory mock of the Svix API",
+		Long: `mock-server serves an in-memory implementation of the parts of the Svix API used by
+the cli, so commands can be scripted and tested without network access. State is lost
+when the server exits.
+
+Messages created through the server are delivered to every matching endpoint, signed
+with the endpoint's secret, and failed deliveries are retried following Svix's retry
+schedule (5s, 5m, 30m, 2h, 5h, 10h, 10h) unless --retry-schedule is set. Delivery
+attempts are recorded and can be inspected with "svix message-attempt".
+
+Any bearer token is accepted. The server can be seeded from a JSON fixtures file:
+	{
+	  "eventTypes": [{"name": "invoice.paid", "description": "An invoice was paid"}],
+	  "applications": [{
+	    "id": "app_test",
+	    "name": "Test app",
+	    "uid": "test",
+	    "endpoints": [{"id": "ep_test", "url": "https://example.com/webhook"}],
+	    "integrations": [{"name": "Zapier"}],
+	    "messages": [{"eventType": "invoice.paid", "payload": {"id": "in_123"}}]
+	  }]
+	}
+
+Example:
+	svix mock-server --fixtures fixtures.json --retry-schedule 1s,5s,10s
+	SVIX_SERVER_URL=http://localhost:8071 SVIX_AUTH_TOKEN=testsk_mock svix application list`,
+		Args: validators.NoArgs(),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			addr, err := cmd.Flags().GetString(addrFlagName)
+			printer.CheckErr(err)
+			fixturesFile, err := cmd.Flags().GetString(fixturesFlagName)
+			printer.CheckErr(err)
+
+			opts := &mock.ServerOptions{}
+			if cmd.Flags().Changed(retryScheduleFlagName) {
+				opts.RetrySchedule, err = cmd.Flags().GetDurationSlice(retryScheduleFlagName)
+				printer.CheckErr(err)
+			}
+			if fixturesFile != "" {
+				opts.Fixtures, err = mock.LoadFixtures(fixturesFile)
+				printer.CheckErr(err)
+			}
+
+			ctx, stop := interruptContext()
+			defer stop()
+
+			server, err := mock.NewServer(addr, opts)
+			printer.CheckErr(err)
+			printer.CheckErr(server.ListenAndServe(ctx))
+		},
+	}
+	msc.cmd.Flags().String(addrFlagName, mock.DefaultServerAddr, "address to serve the mock api on")
+	msc.cmd.Flags().String(fixturesFlagName, "", "JSON file to seed the server with")
+	msc.cmd.Flags().DurationSlice(retryScheduleFlagName, nil, "comma separated delays before each retry of a failed delivery (defaults to Svix's schedule)")
+
+	return msc
+}
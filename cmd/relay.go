@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/relay"
+	"github.com/svix/svix-cli/validators"
+)
+
+type relayCmd struct {
+	cmd *cobra.Command
+}
+
+func newRelayCmd() *relayCmd {
+	addrFlagName := "addr"
+	timeoutFlagName := "timeout"
+
+	rc := &relayCmd{}
+	rc.cmd = &cobra.Command{
+		Use:   "relay",
+		Short: "Run a local webhook relay server",
+	}
+
+	serve := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the webhook relay protocol locally",
+		Long: `serve runs an embedded webhook relay server, allowing "svix listen" to work without
+access to the public Svix relay (e.g. in CI or on air-gapped machines).
+
+Listeners connect to ws://ADDR/api/v1/listen/ and any request made to
+http://ADDR/in/<token>/ is forwarded to the listener registered with that token.
+
+Example:
+	svix relay serve --addr localhost:8090
+	svix listen --local http://localhost:8000/webhook/`,
+		Args: validators.NoArgs(),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			addr, err := cmd.Flags().GetString(addrFlagName)
+			printer.CheckErr(err)
+			timeout, err := cmd.Flags().GetDuration(timeoutFlagName)
+			printer.CheckErr(err)
+
+			ctx, stop := interruptContext()
+			defer stop()
+
+			server := relay.NewServer(addr, &relay.ServerOptions{
+				ResponseTimeout: timeout,
+			})
+			printer.CheckErr(server.ListenAndServe(ctx))
+		},
+	}
+	serve.Flags().String(addrFlagName, relay.DefaultServerAddr, "address to serve the relay on")
+	serve.Flags().Duration(timeoutFlagName, 0, "how long to wait for a listener to respond (defaults to 30s)")
+	rc.cmd.AddCommand(serve)
+
+	return rc
+}
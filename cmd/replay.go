@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/relay"
+	"github.com/svix/svix-cli/validators"
+)
+
+type replayCmd struct {
+	cmd *cobra.Command
+}
+
+func newReplayCmd() *replayCmd {
+	idFlagName := "id"
+	rangeFlagName := "range"
+	localTimeoutFlagName := "local-timeout"
+
+	rc := &replayCmd{}
+	rc.cmd = &cobra.Command{
+		Use:   "replay RECORD_FILE LOCAL_URL",
+		Short: "Replay requests recorded by `svix listen --record`",
+		Long: `replay sends requests recorded with "svix listen --record" to a local URL again,
+this is useful for reproducing bugs without re-triggering the original events.
+
+By default every recorded request is replayed in order, use --id or --range to select
+specific requests. Ranges are 1-based and inclusive, and may be open ended (e.g. 5-).
+As with listen, redirects are not followed and requests time out after --local-timeout.
+
+Example:
+	svix replay requests.jsonl http://localhost:8000/webhook/ --range 3-5`,
+		Args: validators.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			// parse positional args
+			recordFile := args[0]
+			target, err := url.Parse(args[1])
+			if err != nil {
+				printer.CheckErr(fmt.Errorf("invalid local url %s", args[1]))
+			}
+
+			// get flags
+			ids, err := cmd.Flags().GetStringArray(idFlagName)
+			printer.CheckErr(err)
+			var start, end int
+			if cmd.Flags().Changed(rangeFlagName) {
+				rangeFlag, err := cmd.Flags().GetString(rangeFlagName)
+				printer.CheckErr(err)
+				start, end, err = parseRecordRange(rangeFlag)
+				printer.CheckErr(err)
+			}
+			localTimeout, err := cmd.Flags().GetDuration(localTimeoutFlagName)
+			printer.CheckErr(err)
+
+			file, err := os.Open(recordFile)
+			printer.CheckErr(err)
+			defer file.Close()
+			records, err := relay.ReadRecords(file)
+			printer.CheckErr(err)
+
+			selected := selectRecords(records, ids, start, end)
+			if len(selected) == 0 {
+				printer.CheckErr("No matching recorded requests found!")
+			}
+
+			// requests are made the same way listen forwards them
+			httpClient := relay.NewLocalHTTPClient(localTimeout)
+			failed := 0
+			for _, i := range selected {
+				rec := records[i]
+				color.Blue("<- Replaying #%d (%s) %s to: %s", i+1, rec.ID, rec.Method, target.String())
+				res, err := httpClient.Do(rec.Request(target))
+				if err != nil {
+					color.Red("Failed to make request to local server: \n%s\n", err.Error())
+					failed++
+					continue
+				}
+				_, _ = io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				color.Green("-> Received \"%s\" response\n", res.Status)
+			}
+			if failed > 0 {
+				fmt.Fprintf(os.Stderr, "%d of %d requests failed\n", failed, len(selected))
+				os.Exit(1)
+			}
+		},
+	}
+	rc.cmd.Flags().StringArray(idFlagName, []string{}, "id of a recorded request to replay (repeatable)")
+	rc.cmd.Flags().String(rangeFlagName, "", "range of recorded requests to replay, e.g. 3-10")
+	rc.cmd.Flags().Duration(localTimeoutFlagName, 30*time.Second, "how long to wait for the local server to respond")
+	return rc
+}
+
+// parseRecordRange parses a 1-based inclusive range, end is 0 for open ended ranges.
+func parseRecordRange(s string) (start int, end int, err error) {
+	parts := strings.SplitN(s, "-", 2)
+	start, err = strconv.Atoi(parts[0])
+	if err != nil || start < 1 {
+		return 0, 0, fmt.Errorf("invalid range %q", s)
+	}
+	end = start
+	if len(parts) > 1 {
+		if parts[1] == "" {
+			return start, 0, nil
+		}
+		end, err = strconv.Atoi(parts[1])
+		if err != nil || end < start {
+			return 0, 0, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	return start, end, nil
+}
+
+// selectRecords returns the indexes of the records matching any of the ids or the range,
+// or of all records if neither is set.
+func selectRecords(records []*relay.Record, ids []string, start int, end int) []int {
+	idSet := map[string]bool{}
+	for _, id := range ids {
+		idSet[id] = true
+	}
+
+	var selected []int
+	for i, rec := range records {
+		inRange := start > 0 && i+1 >= start && (end == 0 || i+1 <= end)
+		if (len(idSet) == 0 && start == 0) || idSet[rec.ID] || inRange {
+			selected = append(selected, i)
+		}
+	}
+	return selected
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/svix/svix-cli/relay"
+)
+
+func TestParseRecordRange(t *testing.T) {
+	tests := []struct {
+		s       string
+		start   int
+		end     int
+		wantErr bool
+	}{
+		{s: "3", start: 3, end: 3},
+		{s: "3-10", start: 3, end: 10},
+		{s: "5-", start: 5, end: 0},
+		{s: "4-4", start: 4, end: 4},
+		{s: "0", wantErr: true},
+		{s: "10-3", wantErr: true},
+		{s: "-3", wantErr: true},
+		{s: "a-b", wantErr: true},
+		{s: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		start, end, err := parseRecordRange(tt.s)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRecordRange(%q) succeeded, want an error", tt.s)
+			}
+			continue
+		}
+		if err != nil || start != tt.start || end != tt.end {
+			t.Errorf("parseRecordRange(%q) = %d, %d, %v, want %d, %d", tt.s, start, end, err, tt.start, tt.end)
+		}
+	}
+}
+
+func TestSelectRecords(t *testing.T) {
+	var records []*relay.Record
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		records = append(records, &relay.Record{ID: id})
+	}
+	tests := []struct {
+		name  string
+		ids   []string
+		start int
+		end   int
+		want  []int
+	}{
+		{name: "all", want: []int{0, 1, 2, 3, 4}},
+		{name: "ids", ids: []string{"b", "d", "missing"}, want: []int{1, 3}},
+		{name: "range", start: 2, end: 3, want: []int{1, 2}},
+		{name: "open range", start: 4, want: []int{3, 4}},
+		{name: "range past the end", start: 6, end: 8, want: nil},
+		{name: "ids and range", ids: []string{"a"}, start: 5, end: 5, want: []int{0, 4}},
+	}
+	for _, tt := range tests {
+		got := selectRecords(records, tt.ids, tt.start, tt.end)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: selectRecords = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
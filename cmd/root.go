@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 	"time"
 
 	"github.com/spf13/cobra"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/svix/svix-cli/config"
 	"github.com/svix/svix-cli/flags"
+	"github.com/svix/svix-cli/inout"
 	"github.com/svix/svix-cli/version"
 	svix "github.com/svix/svix-webhooks/go"
 )
@@ -37,6 +40,12 @@ func Execute() {
 	cobra.CheckErr(rootCmd.ExecuteContext(ctx))
 }
 
+// interruptContext returns a context that is only cancelled on interrupt, for
+// commands that run until interrupted and so ignore commandTimeout.
+func interruptContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.SetVersionTemplate(version.String())
@@ -61,10 +70,18 @@ func init() {
 	rootCmd.AddCommand(newMessageCmd().cmd)
 	rootCmd.AddCommand(newMessageAttemptCmd().cmd)
 	rootCmd.AddCommand(newVerifyCmd().cmd)
+	rootCmd.AddCommand(newSignCmd().cmd)
+	rootCmd.AddCommand(newSendCmd().cmd)
+	rootCmd.AddCommand(newKeygenCmd().cmd)
 	rootCmd.AddCommand(newOpenCmd().cmd)
 	rootCmd.AddCommand(newListenCmd().cmd)
+	rootCmd.AddCommand(newRelayCmd().cmd)
+	rootCmd.AddCommand(newMockServerCmd().cmd)
+	rootCmd.AddCommand(newReplayCmd().cmd)
 	rootCmd.AddCommand(newImportCmd().cmd)
 	rootCmd.AddCommand(newExportCmd().cmd)
+	rootCmd.AddCommand(newDiffCmd().cmd)
+	rootCmd.AddCommand(newApplyCmd().cmd)
 	rootCmd.AddCommand(newIntegrationCmd().cmd)
 }
 
@@ -89,15 +106,28 @@ func initConfig() {
 }
 
 func getSvixClientOrExit() *svix.Svix {
+	token := getAuthTokenOrExit()
+	opts := getSvixClientOptsOrExit()
+	return svix.New(token, opts)
+}
+
+// getRetryingSvixClientOrExit returns a svix client that retries requests that
+// were rate limited or failed with a server error, for bulk operations.
+func getRetryingSvixClientOrExit(maxRetries int, onRetry func(wait time.Duration)) *svix.Svix {
+	token := getAuthTokenOrExit()
+	opts := getSvixClientOptsOrExit()
+	opts.HTTPClient = inout.NewRetryingHTTPClient(maxRetries, onRetry)
+	return svix.New(token, opts)
+}
+
+func getAuthTokenOrExit() string {
 	token := viper.GetString("auth_token")
 	if token == "" {
 		fmt.Fprintln(os.Stderr, "No SVIX_AUTH_TOKEN found!")
 		fmt.Fprintln(os.Stderr, "Try running `svix login` to get started!")
 		os.Exit(1)
 	}
-
-	opts := getSvixClientOptsOrExit()
-	return svix.New(token, opts)
+	return token
 }
 
 func getSvixClientOptsOrExit() *svix.SvixOptions {
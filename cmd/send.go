@@ -0,0 +1,170 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/sender"
+	"github.com/svix/svix-cli/signature"
+	"github.com/svix/svix-cli/utils"
+	"github.com/svix/svix-cli/validators"
+)
+
+type sendCmd struct {
+	cmd *cobra.Command
+}
+
+func newSendCmd() *sendCmd {
+	secretFlagName := "secret"
+	eventTypeFlagName := "event-type"
+	msgIdFlagName := "msg-id"
+	retryFlagName := "retry"
+	standardWebhooksFlagName := "standard-webhooks"
+
+	sc := &sendCmd{}
+	sc.cmd = &cobra.Command{
+		Use:   "send URL [JSON_PAYLOAD]",
+		Short: "Send a signed test webhook to a url",
+		Long: `send delivers a webhook to URL exactly like Svix would: the payload is POSTed with the
+svix-id, svix-timestamp and svix-signature headers, signed with --secret, and Svix's
+user agent. The response is printed, and the command fails if it isn't a 2xx.
+
+The secret can be a symmetric whsec_ secret, or a whsk_ ed25519 secret key for v1a
+signatures. With --standard-webhooks, the webhook-* header names are used instead.
+
+If no payload is given (as an argument or on stdin) a payload of {"type": EVENT_TYPE} is sent.
+
+With --retry, failed deliveries are retried following Svix's retry schedule compressed
+so that an hour becomes a second (1s, 1s, 1s, 2s, 5s, 10s, 10s).
+
+Example:
+	svix send http://localhost:8000/webhook/ --secret whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw \
+		--event-type invoice.paid '{"type":"invoice.paid","id":"in_123"}'`,
+		Args: validators.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			// parse args
+			url := args[0]
+			var payload []byte
+			if len(args) > 1 {
+				payload = []byte(args[1])
+			} else {
+				var err error
+				payload, err = utils.ReadStdin()
+				printer.CheckErr(err)
+			}
+
+			// ensure all flags are set
+			if !cmd.Flags().Changed(secretFlagName) {
+				printer.CheckErr(fmt.Errorf("Secret required for signing!"))
+			} else if !cmd.Flags().Changed(eventTypeFlagName) {
+				printer.CheckErr(fmt.Errorf("Event type required!"))
+			}
+
+			// get flags
+			secret, err := cmd.Flags().GetString(secretFlagName)
+			printer.CheckErr(err)
+			eventType, err := cmd.Flags().GetString(eventTypeFlagName)
+			printer.CheckErr(err)
+			msgID, err := cmd.Flags().GetString(msgIdFlagName)
+			printer.CheckErr(err)
+			if msgID == "" {
+				msgID = utils.NewID("msg")
+			}
+			retry, err := cmd.Flags().GetBool(retryFlagName)
+			printer.CheckErr(err)
+
+			if len(payload) <= 0 {
+				payload, err = json.Marshal(map[string]string{"type": eventType})
+				printer.CheckErr(err)
+			} else if !json.Valid(payload) {
+				printer.CheckErr("Payload must be valid json!")
+			}
+
+			standardWebhooks, err := cmd.Flags().GetBool(standardWebhooksFlagName)
+			printer.CheckErr(err)
+			headerPrefix := signature.SvixHeaderPrefix
+			if standardWebhooks {
+				headerPrefix = signature.StandardHeaderPrefix
+			}
+
+			key, err := signature.ParseKey(secret)
+			if err != nil {
+				printer.CheckErr(fmt.Errorf("Failed to parse signing secret: %s", err.Error()))
+			}
+			if !key.CanSign() {
+				printer.CheckErr("Public keys can't sign, use the whsk_ secret key!")
+			}
+
+			var schedule []time.Duration
+			if retry {
+				schedule = sender.CompressedRetrySchedule()
+			}
+
+			// retries can outlast the command timeout
+			ctx, stop := interruptContext()
+			defer stop()
+
+			client := &http.Client{
+				Timeout: sender.Timeout,
+			}
+			for attempt := 0; ; attempt++ {
+				fmt.Fprintf(os.Stderr, "Sending %s (%s) to %s\n", msgID, eventType, url)
+				status, err := sendWebhook(ctx, client, printer, url, key, headerPrefix, msgID, payload)
+				if err == nil && status >= 200 && status < 300 {
+					return
+				}
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "Error:", err)
+				}
+				if attempt >= len(schedule) {
+					os.Exit(1)
+				}
+
+				fmt.Fprintf(os.Stderr, "Retrying in %s...\n", schedule[attempt])
+				select {
+				case <-time.After(schedule[attempt]):
+				case <-ctx.Done():
+					os.Exit(1)
+				}
+			}
+		},
+	}
+	sc.cmd.Flags().String(secretFlagName, "", "signing secret of the endpoint, or a whsk_ secret key (required)")
+	sc.cmd.Flags().String(eventTypeFlagName, "", "event type of the message (required)")
+	sc.cmd.Flags().String(msgIdFlagName, "", "msg id header (defaults to a random id)")
+	sc.cmd.Flags().Bool(standardWebhooksFlagName, false, "use the Standard Webhooks webhook-* header names")
+	sc.cmd.Flags().Bool(retryFlagName, false, "retry failed deliveries on a compressed version of Svix's retry schedule")
+	return sc
+}
+
+// sendWebhook makes a single delivery attempt and prints the response, each attempt is signed with the current time.
+func sendWebhook(ctx context.Context, client *http.Client, printer *pretty.Printer, url string, key *signature.Key, headerPrefix string, msgID string, payload []byte) (int, error) {
+	req, err := sender.NewRequest(ctx, url, key, headerPrefix, msgID, payload, nil)
+	if err != nil {
+		return 0, err
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	fmt.Printf("%s %s\n", res.Proto, res.Status)
+	if len(body) > 0 {
+		printer.Print(body)
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/flags"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/signature"
+	"github.com/svix/svix-cli/utils"
+	"github.com/svix/svix-cli/validators"
+)
+
+type signCmd struct {
+	cmd *cobra.Command
+}
+
+func newSignCmd() *signCmd {
+	secretFlagName := "secret"
+	msgIdFlagName := "msg-id"
+	timestampFlagName := "timestamp"
+	formatFlagName := "format"
+	standardWebhooksFlagName := "standard-webhooks"
+
+	format := "json"
+
+	sc := &signCmd{}
+	sc.cmd = &cobra.Command{
+		Use:   "sign [JSON_PAYLOAD]",
+		Short: "Generate the signature headers of a webhook message",
+		Long: `sign generates the svix-id, svix-timestamp and svix-signature headers Svix would send
+with a payload, so signed requests can be crafted by hand to test a webhook receiver.
+
+The secret can be a symmetric whsec_ secret, or a whsk_ ed25519 secret key (see "svix keygen")
+for v1a asymmetric signatures. With --standard-webhooks the headers are named webhook-id,
+webhook-timestamp and webhook-signature, as in the Standard Webhooks spec.
+
+The headers are output as JSON, as shell exports (--format env) or as curl -H flags (--format curl).
+
+Example:
+	svix sign --secret whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw '{"type":"invoice.paid"}'
+	eval curl $(svix sign --format curl --secret whsec_... "$PAYLOAD") -d "$PAYLOAD" http://localhost:8000/webhook/`,
+		Args: validators.RangeArgs(0, 1),
+		Run: func(cmd *cobra.Command, args []string) {
+			printer := pretty.NewPrinter(getPrinterOptions(cmd))
+
+			// parse args
+			var payload []byte
+			if len(args) > 0 {
+				payload = []byte(args[0])
+			} else {
+				var err error
+				payload, err = utils.ReadStdin()
+				printer.CheckErr(err)
+			}
+
+			if len(payload) <= 0 {
+				printer.CheckErr("No json payload provided!")
+			}
+
+			if !cmd.Flags().Changed(secretFlagName) {
+				printer.CheckErr(fmt.Errorf("Secret required for signing!"))
+			}
+
+			// get flags
+			secret, err := cmd.Flags().GetString(secretFlagName)
+			printer.CheckErr(err)
+			msgID, err := cmd.Flags().GetString(msgIdFlagName)
+			printer.CheckErr(err)
+			if msgID == "" {
+				msgID = utils.NewID("msg")
+			}
+			timestamp := time.Now()
+			if cmd.Flags().Changed(timestampFlagName) {
+				unix, err := cmd.Flags().GetInt64(timestampFlagName)
+				printer.CheckErr(err)
+				timestamp = time.Unix(unix, 0)
+			}
+			headerPrefix := signature.SvixHeaderPrefix
+			standardWebhooks, err := cmd.Flags().GetBool(standardWebhooksFlagName)
+			printer.CheckErr(err)
+			if standardWebhooks {
+				headerPrefix = signature.StandardHeaderPrefix
+			}
+
+			key, err := signature.ParseKey(secret)
+			if err != nil {
+				printer.CheckErr(fmt.Errorf("Failed to parse signing secret: %s", err.Error()))
+			}
+			headers, err := key.Headers(headerPrefix, msgID, timestamp, payload)
+			printer.CheckErr(err)
+
+			names := []string{headerPrefix + "id", headerPrefix + "timestamp", headerPrefix + "signature"}
+			switch format {
+			case "env":
+				for _, name := range names {
+					envName := strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+					fmt.Printf("export %s=%q\n", envName, headers[name])
+				}
+			case "curl":
+				curlFlags := make([]string, len(names))
+				for i, name := range names {
+					curlFlags[i] = fmt.Sprintf("-H %q", name+": "+headers[name])
+				}
+				fmt.Println(strings.Join(curlFlags, " "))
+			default:
+				printer.Print(headers)
+			}
+		},
+	}
+	sc.cmd.Flags().String(secretFlagName, "", "signing secret of the endpoint, or a whsk_ secret key (required)")
+	sc.cmd.Flags().String(msgIdFlagName, "", "msg id header (defaults to a random id)")
+	sc.cmd.Flags().Int64(timestampFlagName, 0, "timestamp header in unix seconds (defaults to now)")
+	sc.cmd.Flags().Var(flags.NewEnum(&format, "json", "env", "curl"), formatFlagName, "json|env|curl")
+	sc.cmd.Flags().Bool(standardWebhooksFlagName, false, "use the Standard Webhooks webhook-* header names")
+	return sc
+}
@@ -2,94 +2,249 @@ package cmd
 
 import (
 	"fmt"
-	"net/http"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/svix/svix-cli/capture"
 	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/signature"
 	"github.com/svix/svix-cli/utils"
 	"github.com/svix/svix-cli/validators"
-	svix "github.com/svix/svix-webhooks/go"
+)
+
+// verify's exit codes, so scripts can tell a bad signature from a stale one
+const (
+	verifyExitError            = 1
+	verifyExitInvalidSignature = 2
+	verifyExitStaleTimestamp   = 3
 )
 
 type verifyCmd struct {
 	cmd *cobra.Command
 }
 
+// timestampCheck is how a message's timestamp is checked: within tolerance of
+// now, or not at all.
+type timestampCheck struct {
+	now       time.Time
+	tolerance time.Duration
+	ignore    bool
+}
+
+func (c *timestampCheck) check(timestamp time.Time) error {
+	if c.ignore {
+		return nil
+	}
+	return signature.CheckTimestamp(timestamp, c.now, c.tolerance)
+}
+
+// verifyExitCode is the exit code for a failed verification.
+func verifyExitCode(err error) int {
+	switch err {
+	case signature.ErrNoMatchingSignature:
+		return verifyExitInvalidSignature
+	case signature.ErrMessageTooOld, signature.ErrMessageTooNew:
+		return verifyExitStaleTimestamp
+	default:
+		return verifyExitError
+	}
+}
+
+// exitVerifyErr prints why verification failed and exits with the matching exit code.
+func exitVerifyErr(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(verifyExitCode(err))
+}
+
 func newVerifyCmd() *verifyCmd {
 	secretFlagName := "secret"
 	signatureFlagName := "signature"
 	msgIdFlagName := "msg-id"
 	timestampFlagName := "timestamp"
+	requestFlagName := "request"
+	explainFlagName := "explain"
+	batchFlagName := "batch"
+	toleranceFlagName := "tolerance"
+	nowFlagName := "now"
+	ignoreTimestampFlagName := "ignore-timestamp"
 	ac := &verifyCmd{}
 	ac.cmd = &cobra.Command{
 		Use:   "verify [JSON_PAYLOAD]",
 		Short: "Verify the signature of a webhook message",
-		Args:  validators.RangeArgs(0, 1),
+		Long: `verify checks the signature of a webhook message against the endpoint's signing secret.
+Symmetric whsec_ secrets verify v1 signatures, and ed25519 whpk_ public keys (or whsk_
+secret keys) verify the v1a asymmetric signatures of the Standard Webhooks spec.
+
+The message can be given as a payload and the --msg-id, --timestamp and --signature
+flags, or with --request as a captured request, in which case the svix-* (or
+webhook-*) headers are read from it and its body is verified byte for byte.
+Captured requests can be raw HTTP/1.1 request dumps, HAR files or entries, or
+JSON documents of the form {"headers": {"svix-id": "..."}, "body": "..."}.
+
+With --explain, a failed verification is diagnosed: each signature checked is listed
+along with the expected one, the timestamp's skew from now is shown, and common
+mistakes are pointed out, such as a malformed secret or a payload that only differs
+from the signed one by whitespace or a trailing newline.
+
+With --batch, every line of a JSON lines file is verified, each line being a record of
+the form {"id": "msg_...", "timestamp": 1700000000, "signature": "v1,...", "payload": ...}.
+A result is printed per line, followed by a summary, and the command fails if any
+record isn't valid. --secret can be repeated to try several secrets, e.g. across a
+secret rotation.
+
+A message's timestamp must be within --tolerance (5m by default) of now, to guard
+against replayed messages. Historical messages can be verified by setting --now to
+the unix timestamp they were received at, or by skipping the check with
+--ignore-timestamp.
+
+verify exits with 1 on errors, 2 if the signature is invalid, and 3 if the signature
+is valid but the timestamp isn't within tolerance. With --batch, the exit code is
+that of the most severe failure: 2 for any invalid signature, then 1 for any
+unreadable record, then 3 for any stale timestamp.
+
+Example:
+	svix verify --secret whsec_... --request webhook.har --explain
+	svix verify --secret whsec_old... --secret whsec_new... --batch deliveries.jsonl
+	svix verify --secret whsec_... --request webhook.http --now 1700000000 --tolerance 1h`,
+		Args: validators.RangeArgs(0, 1),
 		Run: func(cmd *cobra.Command, args []string) {
 			printer := pretty.NewPrinter(getPrinterOptions(cmd))
 
+			requestFile, err := cmd.Flags().GetString(requestFlagName)
+			printer.CheckErr(err)
+			batchFile, err := cmd.Flags().GetString(batchFlagName)
+			printer.CheckErr(err)
+
+			tsCheck := &timestampCheck{now: time.Now()}
+			tsCheck.tolerance, err = cmd.Flags().GetDuration(toleranceFlagName)
+			printer.CheckErr(err)
+			if tsCheck.tolerance < 0 {
+				printer.CheckErr(fmt.Errorf("--%s can't be negative", toleranceFlagName))
+			}
+			tsCheck.ignore, err = cmd.Flags().GetBool(ignoreTimestampFlagName)
+			printer.CheckErr(err)
+			if cmd.Flags().Changed(nowFlagName) {
+				now, err := cmd.Flags().GetString(nowFlagName)
+				printer.CheckErr(err)
+				tsCheck.now, err = signature.ParseTimestamp(now)
+				if err != nil {
+					printer.CheckErr(fmt.Errorf("--now must be a unix timestamp in seconds"))
+				}
+			}
+
+			if !cmd.Flags().Changed(secretFlagName) {
+				printer.CheckErr(fmt.Errorf("Secret required for verification!"))
+			}
+			secrets, err := cmd.Flags().GetStringArray(secretFlagName)
+			printer.CheckErr(err)
+			keys := make([]*signature.Key, len(secrets))
+			for i, secret := range secrets {
+				keys[i], err = signature.ParseKey(secret)
+				if err != nil {
+					printer.CheckErr(fmt.Errorf("Failed to parse signing secret: %s", err.Error()))
+				}
+			}
+
+			if batchFile != "" {
+				if len(args) > 0 || requestFile != "" {
+					printer.CheckErr("A payload or --request can't be given with --batch!")
+				}
+				r := os.Stdin
+				if batchFile != "-" {
+					r, err = os.Open(batchFile)
+					printer.CheckErr(err)
+					defer r.Close()
+				}
+				summary, err := verifyBatch(r, os.Stdout, keys, tsCheck)
+				printer.CheckErr(err)
+				if code := summary.exitCode(); code != 0 {
+					os.Exit(code)
+				}
+				return
+			}
+
 			// parse args
 			var payload []byte
-			if len(args) > 0 {
-				payload = []byte(args[0])
-			} else {
-				var err error
-				payload, err = utils.ReadStdin()
+			var msgID, timestamp, sig string
+			if requestFile != "" {
+				if len(args) > 0 {
+					printer.CheckErr("A payload can't be given with --request!")
+				}
+				req, err := capture.Load(requestFile)
 				printer.CheckErr(err)
-			}
+				payload = req.Body
+				msgID, timestamp, sig = capture.SignatureHeaders(req.Header)
+			} else {
+				if len(args) > 0 {
+					payload = []byte(args[0])
+				} else {
+					payload, err = utils.ReadStdin()
+					printer.CheckErr(err)
+				}
 
-			if len(payload) <= 0 {
-				printer.CheckErr("No json payload provided!")
+				if len(payload) <= 0 {
+					printer.CheckErr("No json payload provided!")
+				}
 			}
 
-			// ensure all flags are set
-			var err error
-			if !cmd.Flags().Changed(secretFlagName) {
-				err = fmt.Errorf("Secret required for verification!")
-			} else if !cmd.Flags().Changed(signatureFlagName) {
+			// get flags, which take precedence over the request's headers
+			if cmd.Flags().Changed(msgIdFlagName) {
+				msgID, err = cmd.Flags().GetString(msgIdFlagName)
+				printer.CheckErr(err)
+			}
+			if cmd.Flags().Changed(timestampFlagName) {
+				timestamp, err = cmd.Flags().GetString(timestampFlagName)
+				printer.CheckErr(err)
+			}
+			if cmd.Flags().Changed(signatureFlagName) {
+				sig, err = cmd.Flags().GetString(signatureFlagName)
+				printer.CheckErr(err)
+			}
+			// ensure everything is set
+			if sig == "" {
 				err = fmt.Errorf("Signature required for verification!")
-			} else if !cmd.Flags().Changed(timestampFlagName) {
+			} else if timestamp == "" {
 				err = fmt.Errorf("Timestamp required for verification!")
-			} else if !cmd.Flags().Changed(msgIdFlagName) {
+			} else if msgID == "" {
 				err = fmt.Errorf("Message ID required for verification")
 			}
 			printer.CheckErr(err)
 
-			// get flags
-			secret, err := cmd.Flags().GetString(secretFlagName)
-			printer.CheckErr(err)
-			msgID, err := cmd.Flags().GetString(msgIdFlagName)
-			printer.CheckErr(err)
-			timestamp, err := cmd.Flags().GetString(timestampFlagName)
+			explain, err := cmd.Flags().GetBool(explainFlagName)
 			printer.CheckErr(err)
-			signature, err := cmd.Flags().GetString(signatureFlagName)
-			printer.CheckErr(err)
-
-			headers := http.Header{}
-			headers.Set("svix-id", msgID)
-			headers.Set("svix-timestamp", timestamp)
-			headers.Set("svix-signature", signature)
+			if explain {
+				if len(secrets) > 1 {
+					printer.CheckErr("--explain takes a single --secret!")
+				}
+				err := explainVerification(os.Stdout, secrets[0], msgID, timestamp, sig, payload, tsCheck)
+				if err != nil {
+					os.Exit(verifyExitCode(err))
+				}
+				return
+			}
 
-			wh, err := svix.NewWebhook(secret)
-			if err != nil {
-				printer.CheckErr(fmt.Errorf("Failed to parse signing secret: %s", err.Error()))
+			ts, err := signature.ParseTimestamp(timestamp)
+			printer.CheckErr(err)
+			if _, err := verifyWithKeys(keys, msgID, ts, payload, sig); err != nil {
+				exitVerifyErr(err)
 			}
-			err = wh.Verify(payload, headers)
-			if err != nil {
-				errNoTimestamp := wh.VerifyIgnoringTimestamp(payload, headers)
-				if errNoTimestamp == nil {
-					fmt.Println("Signature is valid but failed timestamp verification.")
-					os.Exit(1)
-				}
-				printer.CheckErr(errNoTimestamp)
+			if err := tsCheck.check(ts); err != nil {
+				fmt.Println("Signature is valid but failed timestamp verification.")
+				exitVerifyErr(err)
 			}
 			fmt.Println("Message Signature Is Valid!")
 		},
 	}
-	ac.cmd.Flags().String(secretFlagName, "", "signing secret of the endpoint (required)")
-	ac.cmd.Flags().String(msgIdFlagName, "", "msg id header (required)")
-	ac.cmd.Flags().String(timestampFlagName, "", "timestamp header (required)")
-	ac.cmd.Flags().String(signatureFlagName, "", "signature header (required)")
+	ac.cmd.Flags().StringArray(secretFlagName, nil, "signing secret of the endpoint, or a whpk_ public key, can be repeated to try several (required)")
+	ac.cmd.Flags().String(msgIdFlagName, "", "msg id header (required unless in --request)")
+	ac.cmd.Flags().String(timestampFlagName, "", "timestamp header (required unless in --request)")
+	ac.cmd.Flags().String(signatureFlagName, "", "signature header (required unless in --request)")
+	ac.cmd.Flags().String(requestFlagName, "", "file of a captured request to verify (raw HTTP, HAR or JSON)")
+	ac.cmd.Flags().Bool(explainFlagName, false, "explain why verification failed")
+	ac.cmd.Flags().String(batchFlagName, "", "JSON lines file of messages to verify, or - for stdin")
+	ac.cmd.Flags().Duration(toleranceFlagName, signature.DefaultTolerance, "how far the timestamp can be from now")
+	ac.cmd.Flags().String(nowFlagName, "", "unix timestamp to check the timestamp against, instead of the current time")
+	ac.cmd.Flags().Bool(ignoreTimestampFlagName, false, "don't check the timestamp, only the signature")
 	return ac
 }
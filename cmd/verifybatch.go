@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/svix/svix-cli/signature"
+)
+
+// maxBatchLineSize is the longest line verify --batch accepts, payloads can be large
+const maxBatchLineSize = 10 << 20 // 10MiB
+
+// batchRecord is a line of a verify --batch file. The timestamp can be a string
+// or a number, a string payload is verified as is and any other json payload is
+// verified exactly as written on the line.
+type batchRecord struct {
+	ID        string          `json:"id"`
+	Timestamp json.RawMessage `json:"timestamp"`
+	Signature string          `json:"signature"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+type batchSummary struct {
+	Total   int
+	Valid   int
+	Invalid int
+	Stale   int
+	Errors  int
+}
+
+// exitCode is the exit code of the most severe failure in the batch: an invalid
+// signature, then an unreadable record, then a stale timestamp.
+func (s *batchSummary) exitCode() int {
+	switch {
+	case s.Invalid > 0:
+		return verifyExitInvalidSignature
+	case s.Errors > 0:
+		return verifyExitError
+	case s.Stale > 0:
+		return verifyExitStaleTimestamp
+	default:
+		return 0
+	}
+}
+
+// verifyBatch verifies every record in r against the candidate keys, writing a
+// line per record and a summary to w.
+func verifyBatch(r io.Reader, w io.Writer, keys []*signature.Key, tsCheck *timestampCheck) (*batchSummary, error) {
+	summary := &batchSummary{}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxBatchLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		summary.Total++
+
+		var record batchRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			summary.Errors++
+			fmt.Fprintf(w, "%d\t-\terror: invalid record: %s\n", line, err)
+			continue
+		}
+		id := record.ID
+		if id == "" {
+			id = "-"
+		}
+		ts, payload, err := record.parse()
+		if err != nil {
+			summary.Errors++
+			fmt.Fprintf(w, "%d\t%s\terror: %s\n", line, id, err)
+			continue
+		}
+
+		keyIdx, err := verifyWithKeys(keys, record.ID, ts, payload, record.Signature)
+		if err != nil {
+			summary.Invalid++
+			fmt.Fprintf(w, "%d\t%s\tinvalid: %s\n", line, id, err)
+			continue
+		}
+		if err := tsCheck.check(ts); err != nil {
+			summary.Stale++
+			fmt.Fprintf(w, "%d\t%s\tstale: %s (secret #%d)\n", line, id, err, keyIdx+1)
+			continue
+		}
+		summary.Valid++
+		fmt.Fprintf(w, "%d\t%s\tvalid (secret #%d)\n", line, id, keyIdx+1)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(w, "\n%d records: %d valid, %d invalid, %d stale, %d errors\n",
+		summary.Total, summary.Valid, summary.Invalid, summary.Stale, summary.Errors)
+	return summary, nil
+}
+
+func (r *batchRecord) parse() (time.Time, []byte, error) {
+	if r.ID == "" || r.Signature == "" || len(r.Timestamp) == 0 {
+		return time.Time{}, nil, fmt.Errorf("id, timestamp and signature are required")
+	}
+	timestamp := string(r.Timestamp)
+	var s string
+	if err := json.Unmarshal(r.Timestamp, &s); err == nil {
+		timestamp = s
+	}
+	ts, err := signature.ParseTimestamp(timestamp)
+	if err != nil {
+		return time.Time{}, nil, err
+	}
+
+	var payload []byte
+	if err := json.Unmarshal(r.Payload, &s); err == nil {
+		payload = []byte(s)
+	} else {
+		payload = r.Payload
+	}
+	return ts, payload, nil
+}
+
+// verifyWithKeys verifies a signature with each candidate key in turn,
+// returning the index of the first key that verifies it.
+func verifyWithKeys(keys []*signature.Key, msgID string, timestamp time.Time, payload []byte, sig string) (int, error) {
+	for i, key := range keys {
+		if key.Verify(msgID, timestamp, payload, sig) == nil {
+			return i, nil
+		}
+	}
+	return -1, signature.ErrNoMatchingSignature
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/svix/svix-cli/signature"
+)
+
+// payloadVariant is a common accidental modification of a payload, e.g. by an
+// editor or a framework parsing and reserializing the body.
+type payloadVariant struct {
+	description string
+	transform   func(payload []byte) []byte
+}
+
+var payloadVariants = []payloadVariant{
+	{"with its trailing newline removed", func(p []byte) []byte {
+		return bytes.TrimSuffix(bytes.TrimSuffix(p, []byte("\n")), []byte("\r"))
+	}},
+	{"with a trailing newline added", func(p []byte) []byte {
+		return append(append([]byte{}, p...), '\n')
+	}},
+	{"with leading and trailing whitespace removed", bytes.TrimSpace},
+	{"with CRLF line endings replaced by LF", func(p []byte) []byte {
+		return bytes.ReplaceAll(p, []byte("\r\n"), []byte("\n"))
+	}},
+	{"as compact json", func(p []byte) []byte {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, p); err != nil {
+			return p
+		}
+		return buf.Bytes()
+	}},
+}
+
+// explainVerification writes a diagnosis of why a signature does or doesn't
+// verify, and returns the reason it failed, if it did.
+func explainVerification(w io.Writer, secret string, msgID string, timestamp string, sigHeader string, payload []byte, tsCheck *timestampCheck) error {
+	// secret
+	fmt.Fprintf(w, "Secret:\n")
+	key, secretProblems := explainSecret(secret)
+	for _, problem := range secretProblems {
+		fmt.Fprintf(w, "  ! %s\n", problem)
+	}
+	if key == nil {
+		fmt.Fprintf(w, "\nSignature can't be checked without a valid secret.\n")
+		return errors.New("invalid signing secret")
+	}
+	switch {
+	case !key.Asymmetric():
+		fmt.Fprintf(w, "  %d byte symmetric secret, verifying v1 signatures\n", key.Size())
+	case key.CanSign():
+		fmt.Fprintf(w, "  ed25519 secret key, verifying v1a signatures\n")
+	default:
+		fmt.Fprintf(w, "  ed25519 public key, verifying v1a signatures\n")
+	}
+
+	// timestamp
+	fmt.Fprintf(w, "Timestamp:\n")
+	ts, err := signature.ParseTimestamp(timestamp)
+	if err != nil {
+		fmt.Fprintf(w, "  ! %q is not a unix timestamp in seconds\n", timestamp)
+		return signature.ErrInvalidTimestamp
+	}
+	skew := tsCheck.now.Sub(ts).Round(time.Second)
+	relative := fmt.Sprintf("%s ago", skew)
+	if skew < 0 {
+		relative = fmt.Sprintf("%s in the future", -skew)
+	}
+	tsErr := tsCheck.check(ts)
+	switch {
+	case tsCheck.ignore:
+		fmt.Fprintf(w, "  %s (%s, not checked)\n", ts.UTC().Format(time.RFC3339), relative)
+	case tsErr != nil:
+		fmt.Fprintf(w, "  ! %s is %s, beyond the %s tolerance\n", ts.UTC().Format(time.RFC3339), relative, tsCheck.tolerance)
+	default:
+		fmt.Fprintf(w, "  %s (%s, within tolerance)\n", ts.UTC().Format(time.RFC3339), relative)
+	}
+
+	// signatures
+	fmt.Fprintf(w, "Signatures:\n")
+	if key.CanSign() {
+		expected, err := key.Sign(msgID, ts, payload)
+		if err != nil {
+			fmt.Fprintf(w, "  ! failed to sign: %s\n", err)
+			return err
+		}
+		fmt.Fprintf(w, "  expected %s (msg id %q, %d byte payload)\n", expected, msgID, len(payload))
+	} else {
+		fmt.Fprintf(w, "  expected a v1a signature (msg id %q, %d byte payload)\n", msgID, len(payload))
+	}
+	passed := versionedSignatures(w, sigHeader, key.Version())
+	matched := false
+	for _, sig := range passed {
+		if key.VerifySignature(msgID, ts, payload, sig) {
+			fmt.Fprintf(w, "  %s matches\n", sig)
+			matched = true
+		} else {
+			fmt.Fprintf(w, "  %s doesn't match\n", sig)
+		}
+	}
+
+	// payload
+	if !matched {
+		fmt.Fprintf(w, "Payload:\n")
+		found := false
+		for _, variant := range payloadVariants {
+			modified := variant.transform(payload)
+			if bytes.Equal(modified, payload) {
+				continue
+			}
+			for _, sig := range passed {
+				if key.VerifySignature(msgID, ts, modified, sig) {
+					fmt.Fprintf(w, "  ! the signature matches the payload %s, make sure the body is verified exactly as it was received\n", variant.description)
+					found = true
+					break
+				}
+			}
+		}
+		if !found {
+			fmt.Fprintf(w, "  no whitespace or newline changes to the payload match, check the secret and msg id are for this message\n")
+		}
+	}
+
+	fmt.Fprintln(w)
+	switch {
+	case !matched:
+		fmt.Fprintln(w, "No matching signature found.")
+		return signature.ErrNoMatchingSignature
+	case tsErr != nil:
+		fmt.Fprintln(w, "Signature is valid but failed timestamp verification.")
+		return tsErr
+	default:
+		fmt.Fprintln(w, "Message Signature Is Valid!")
+		return nil
+	}
+}
+
+// explainSecret parses the secret, returning problems with its format. key
+// is nil if it couldn't be parsed at all.
+func explainSecret(secret string) (key *signature.Key, problems []string) {
+	if strings.HasPrefix(secret, signature.PublicKeyPrefix) || strings.HasPrefix(secret, signature.SecretKeyPrefix) {
+		key, err := signature.ParseKey(secret)
+		if err != nil {
+			return nil, []string{fmt.Sprintf("invalid ed25519 key: %s", err)}
+		}
+		return key, nil
+	}
+
+	if !strings.HasPrefix(secret, signature.SymmetricPrefix) {
+		problems = append(problems, "secret doesn't start with whsec_ (or whpk_/whsk_), make sure it's the endpoint's signing secret")
+	}
+	raw := strings.TrimPrefix(secret, signature.SymmetricPrefix)
+	key, err := signature.ParseKey(raw)
+	if err != nil {
+		switch {
+		case isBase64(base64.URLEncoding, raw) || isBase64(base64.RawURLEncoding, raw):
+			problems = append(problems, "secret is url-safe base64, signing secrets use standard base64 (+ and / instead of - and _)")
+		case isBase64(base64.RawStdEncoding, raw):
+			problems = append(problems, "secret is missing its base64 padding (=)")
+		default:
+			problems = append(problems, fmt.Sprintf("secret is not valid base64: %s", err))
+		}
+		return nil, problems
+	}
+	if key.Size() < 24 || key.Size() > 75 {
+		problems = append(problems, fmt.Sprintf("secret decodes to %d bytes, signing secrets are 24 to 75 bytes", key.Size()))
+	}
+	return key, problems
+}
+
+func isBase64(enc *base64.Encoding, s string) bool {
+	_, err := enc.DecodeString(s)
+	return err == nil
+}
+
+// versionedSignatures returns the signatures of a version in a space separated signature header, noting any it skips.
+func versionedSignatures(w io.Writer, header string, version string) []string {
+	var signatures []string
+	for _, sig := range strings.Split(header, " ") {
+		if sig == "" {
+			continue
+		}
+		parts := strings.SplitN(sig, ",", 2)
+		switch {
+		case len(parts) < 2:
+			fmt.Fprintf(w, "  %s skipped, expected version,signature\n", sig)
+		case parts[0] != version:
+			fmt.Fprintf(w, "  %s skipped, the key verifies %s signatures\n", sig, version)
+		default:
+			signatures = append(signatures, sig)
+		}
+	}
+	if len(signatures) == 0 {
+		fmt.Fprintf(w, "  ! no %s signatures found in %q\n", version, header)
+	}
+	return signatures
+}
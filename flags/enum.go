@@ -25,3 +25,8 @@ func (f *enum) Set(value string) error {
 
 	return fmt.Errorf("expected one of the following %q", f.options)
 }
+
+// Type implements pflag.Value, so enums can be registered directly on cobra commands.
+func (f *enum) Type() string {
+	return "enum"
+}
@@ -5,6 +5,7 @@ go 1.16
 require (
 	github.com/BurntSushi/toml v0.3.1
 	github.com/araddon/dateparse v0.0.0-20210429162001-6b43995a97de
+	github.com/chzyer/readline v0.0.0-20180603132655-2972be24d48e
 	github.com/fatih/color v1.13.0
 	github.com/fsnotify/fsnotify v1.5.1 // indirect
 	github.com/gorilla/websocket v1.4.2
@@ -16,4 +17,5 @@ require (
 	github.com/spf13/viper v1.10.0
 	github.com/svix/svix-webhooks v1.12.1-0.20230926011735-7bc1d38200ec
 	github.com/tidwall/pretty v1.1.1
+	gopkg.in/yaml.v2 v2.4.0
 )
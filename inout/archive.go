@@ -0,0 +1,400 @@
+package inout
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// ArchiveVersion is the version of the archive format written by ExportArchive,
+// archives of newer versions can't be imported.
+const ArchiveVersion = 1
+
+// Archive is a snapshot of an organization's event types, applications,
+// endpoints and integrations. IDs are those of the exported organization, and
+// are only kept for reference since imported resources get new IDs.
+type Archive struct {
+	Version      int                  `json:"version"`
+	ExportedAt   time.Time            `json:"exportedAt"`
+	EventTypes   []svix.EventTypeIn   `json:"eventTypes"`
+	Applications []ArchiveApplication `json:"applications"`
+}
+
+type ArchiveApplication struct {
+	ID           string               `json:"id"`
+	Application  svix.ApplicationIn   `json:"application"`
+	Endpoints    []ArchiveEndpoint    `json:"endpoints"`
+	Integrations []ArchiveIntegration `json:"integrations"`
+}
+
+type ArchiveEndpoint struct {
+	ID       string          `json:"id"`
+	Endpoint svix.EndpointIn `json:"endpoint"`
+	// Headers are the endpoint's custom headers, sensitive headers (such as
+	// Authorization) aren't returned by the API so can't be exported.
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+type ArchiveIntegration struct {
+	ID          string             `json:"id"`
+	Integration svix.IntegrationIn `json:"integration"`
+}
+
+func ReadArchive(reader io.Reader) (*Archive, error) {
+	var archive Archive
+	if err := json.NewDecoder(reader).Decode(&archive); err != nil {
+		return nil, err
+	}
+	if archive.Version < 1 || archive.Version > ArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d, this version of the cli supports up to version %d", archive.Version, ArchiveVersion)
+	}
+	return &archive, nil
+}
+
+func WriteArchive(archive *Archive, writer io.Writer) error {
+	enc := json.NewEncoder(writer)
+	enc.SetIndent("", "  ")
+	return enc.Encode(archive)
+}
+
+// ExportArchive exports the whole organization. Endpoint secrets are only
+// included if includeSecrets is set, otherwise imported endpoints get new ones.
+func ExportArchive(ctx context.Context, sc *svix.Svix, includeSecrets bool) (*Archive, error) {
+	archive := &Archive{
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+	}
+
+	eventTypes, err := GetAllEventTypes(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, et := range eventTypes {
+		archive.EventTypes = append(archive.EventTypes, svix.EventTypeIn{
+			Archived:    et.Archived,
+			Description: et.Description,
+			FeatureFlag: et.FeatureFlag,
+			Name:        et.Name,
+			Schemas:     et.Schemas,
+		})
+	}
+
+	apps, err := getAllApplications(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	for _, app := range apps {
+		exported, err := exportApplication(ctx, sc, app, includeSecrets)
+		if err != nil {
+			return nil, fmt.Errorf("application %s: %s", app.Id, err)
+		}
+		archive.Applications = append(archive.Applications, *exported)
+	}
+	return archive, nil
+}
+
+func exportApplication(ctx context.Context, sc *svix.Svix, app svix.ApplicationOut, includeSecrets bool) (*ArchiveApplication, error) {
+	exported := &ArchiveApplication{
+		ID: app.Id,
+		Application: svix.ApplicationIn{
+			Name:      app.Name,
+			RateLimit: app.RateLimit,
+			Uid:       app.Uid,
+		},
+		Endpoints:    []ArchiveEndpoint{},
+		Integrations: []ArchiveIntegration{},
+	}
+	if len(app.Metadata) > 0 {
+		exported.Application.Metadata = &app.Metadata
+	}
+
+	endpoints, err := getAllEndpoints(ctx, sc, app.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints {
+		in := svix.EndpointIn{
+			Channels:    ep.Channels,
+			Disabled:    ep.Disabled,
+			FilterTypes: ep.FilterTypes,
+			RateLimit:   ep.RateLimit,
+			Uid:         ep.Uid,
+			Url:         ep.Url,
+		}
+		version := ep.Version
+		in.Version.Set(&version)
+		if ep.Description != "" {
+			description := ep.Description
+			in.Description = &description
+		}
+		if len(ep.Metadata) > 0 {
+			metadata := ep.Metadata
+			in.Metadata = &metadata
+		}
+		if includeSecrets {
+			secret, err := sc.Endpoint.GetSecret(ctx, app.Id, ep.Id)
+			if err != nil {
+				return nil, err
+			}
+			in.Secret.Set(&secret.Key)
+		}
+		headers, err := sc.Endpoint.GetHeaders(ctx, app.Id, ep.Id)
+		if err != nil {
+			return nil, err
+		}
+		exported.Endpoints = append(exported.Endpoints, ArchiveEndpoint{
+			ID:       ep.Id,
+			Endpoint: in,
+			Headers:  headers.Headers,
+		})
+	}
+
+	integrations, err := getAllIntegrations(ctx, sc, app.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, integ := range integrations {
+		exported.Integrations = append(exported.Integrations, ArchiveIntegration{
+			ID:          integ.Id,
+			Integration: svix.IntegrationIn{Name: integ.Name},
+		})
+	}
+	return exported, nil
+}
+
+// ImportArchive creates everything in the archive, reporting the outcome of
+// every item. Applications and endpoints that already exist are skipped, or
+// updated if update is set, and integrations are skipped if the application has
+// one with the same name. Applications are matched by uid, or by name if they
+// have none, and endpoints by uid, or by url. A failure to import an item doesn't
+// stop the import, but the endpoints and integrations of an application that
+// failed to import are left out.
+func ImportArchive(ctx context.Context, sc *svix.Svix, archive *Archive, update bool) (*ImportReport, error) {
+	report := newImportReport(false)
+	for i := range archive.EventTypes {
+		et := &archive.EventTypes[i]
+		outcome, err := createOrUpdateEventType(ctx, sc, et, update)
+		report.add("event type "+et.Name, outcome, err)
+	}
+	live, err := getAllApplications(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	matched := map[string]bool{}
+	for i := range archive.Applications {
+		importApplication(ctx, sc, &archive.Applications[i], live, matched, update, report)
+	}
+	return report, nil
+}
+
+func importApplication(ctx context.Context, sc *svix.Svix, app *ArchiveApplication, live []svix.ApplicationOut, matched map[string]bool, update bool, report *ImportReport) {
+	name := "application " + app.Application.Name
+	var err error
+	var liveEndpoints []svix.EndpointOut
+	outcome := OutcomeCreated
+	out := matchApplication(&app.Application, live, matched)
+	if out != nil {
+		matched[out.Id] = true
+		outcome = OutcomeSkipped
+		if liveEndpoints, err = getAllEndpoints(ctx, sc, out.Id); err != nil {
+			report.add(name, "", err)
+			return
+		}
+		if update {
+			outcome = OutcomeUpdated
+			out, err = sc.Application.Update(ctx, out.Id, &app.Application)
+		}
+	} else {
+		out, err = sc.Application.CreateWithOptions(ctx, &app.Application, idempotentPostOptions())
+	}
+	report.add(name, outcome, err)
+	if err != nil {
+		return
+	}
+
+	matchedEndpoints := map[string]bool{}
+	for i := range app.Endpoints {
+		ep := &app.Endpoints[i]
+		outcome, err := importEndpoint(ctx, sc, out.Id, ep, liveEndpoints, matchedEndpoints, update)
+		report.add(name+" endpoint "+ep.Endpoint.Url, outcome, err)
+	}
+
+	existing, err := getAllIntegrations(ctx, sc, out.Id)
+	names := make(map[string]bool, len(existing))
+	for _, integ := range existing {
+		names[integ.Name] = true
+	}
+	for i := range app.Integrations {
+		integ := &app.Integrations[i]
+		integName := name + " integration " + integ.Integration.Name
+		switch {
+		case err != nil:
+			report.add(integName, "", err)
+		case names[integ.Integration.Name]:
+			report.add(integName, OutcomeSkipped, nil)
+		default:
+			_, createErr := sc.Integration.CreateWithOptions(ctx, out.Id, &integ.Integration, idempotentPostOptions())
+			report.add(integName, OutcomeCreated, createErr)
+		}
+	}
+}
+
+func importEndpoint(ctx context.Context, sc *svix.Svix, appID string, ep *ArchiveEndpoint, live []svix.EndpointOut, matched map[string]bool, update bool) (string, error) {
+	in := &ep.Endpoint
+	var err error
+	outcome := OutcomeCreated
+	out := matchArchiveEndpoint(in, live, matched)
+	if out != nil {
+		matched[out.Id] = true
+		if !update {
+			return OutcomeSkipped, nil
+		}
+		outcome = OutcomeUpdated
+		out, err = sc.Endpoint.Update(ctx, appID, out.Id, &svix.EndpointUpdate{
+			Channels:    in.Channels,
+			Description: in.Description,
+			Disabled:    in.Disabled,
+			FilterTypes: in.FilterTypes,
+			Metadata:    in.Metadata,
+			RateLimit:   in.RateLimit,
+			Uid:         in.Uid,
+			Url:         in.Url,
+			Version:     in.Version,
+		})
+		if err == nil {
+			err = importEndpointSecret(ctx, sc, appID, out.Id, in.Secret.Get())
+		}
+	} else {
+		out, err = sc.Endpoint.CreateWithOptions(ctx, appID, in, idempotentPostOptions())
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(ep.Headers) > 0 {
+		if err := sc.Endpoint.UpdateHeaders(ctx, appID, out.Id, &svix.EndpointHeadersIn{Headers: ep.Headers}); err != nil {
+			return "", err
+		}
+	}
+	return outcome, nil
+}
+
+// importEndpointSecret sets the secret of an existing endpoint to the archived
+// one, if it was exported, since updating an endpoint can't change its secret.
+// Rotating keeps the previous secret valid for a while, so it's only done if
+// the secrets differ.
+func importEndpointSecret(ctx context.Context, sc *svix.Svix, appID string, endpointID string, secret *string) error {
+	if secret == nil {
+		return nil
+	}
+	current, err := sc.Endpoint.GetSecret(ctx, appID, endpointID)
+	if err != nil {
+		return err
+	}
+	if current.Key == *secret {
+		return nil
+	}
+	rotate := &svix.EndpointSecretRotateIn{}
+	rotate.Key.Set(secret)
+	return sc.Endpoint.RotateSecretWithOptions(ctx, appID, endpointID, rotate, idempotentPostOptions())
+}
+
+// matchApplication finds the existing application an archived one is imported
+// into: the one with its uid, or if it has none, one without a uid and the same name.
+func matchApplication(app *svix.ApplicationIn, live []svix.ApplicationOut, matched map[string]bool) *svix.ApplicationOut {
+	for i := range live {
+		out := &live[i]
+		if matched[out.Id] {
+			continue
+		}
+		uid, liveUid := app.Uid.Get(), out.Uid.Get()
+		if uid != nil && liveUid != nil && *uid == *liveUid {
+			return out
+		}
+		if uid == nil && liveUid == nil && out.Name == app.Name {
+			return out
+		}
+	}
+	return nil
+}
+
+// matchArchiveEndpoint finds the existing endpoint an archived one is imported
+// into, by uid or if it has none, by url, as matchEndpoint does for desired states.
+func matchArchiveEndpoint(ep *svix.EndpointIn, live []svix.EndpointOut, matched map[string]bool) *svix.EndpointOut {
+	for i := range live {
+		out := &live[i]
+		if matched[out.Id] {
+			continue
+		}
+		uid, liveUid := ep.Uid.Get(), out.Uid.Get()
+		if uid != nil && liveUid != nil && *uid == *liveUid {
+			return out
+		}
+		if uid == nil && liveUid == nil && out.Url == ep.Url {
+			return out
+		}
+	}
+	return nil
+}
+
+func isConflict(err error) bool {
+	sErr, ok := err.(*svix.Error)
+	return ok && sErr.Status() == 409
+}
+
+func getAllApplications(ctx context.Context, sc *svix.Svix) ([]svix.ApplicationOut, error) {
+	var apps []svix.ApplicationOut
+	var iterator *string
+	for {
+		out, err := sc.Application.List(ctx, &svix.ApplicationListOptions{
+			Iterator: iterator,
+		})
+		if err != nil {
+			return nil, err
+		}
+		apps = append(apps, out.Data...)
+		if out.Done || out.Iterator.Get() == nil {
+			return apps, nil
+		}
+		iterator = out.Iterator.Get()
+	}
+}
+
+func getAllEndpoints(ctx context.Context, sc *svix.Svix, appID string) ([]svix.EndpointOut, error) {
+	var endpoints []svix.EndpointOut
+	var iterator *string
+	for {
+		out, err := sc.Endpoint.List(ctx, appID, &svix.EndpointListOptions{
+			Iterator: iterator,
+		})
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, out.Data...)
+		if out.Done || out.Iterator.Get() == nil {
+			return endpoints, nil
+		}
+		iterator = out.Iterator.Get()
+	}
+}
+
+func getAllIntegrations(ctx context.Context, sc *svix.Svix, appID string) ([]svix.IntegrationOut, error) {
+	var integrations []svix.IntegrationOut
+	var iterator *string
+	for {
+		out, err := sc.Integration.List(ctx, appID, &svix.IntegrationListOptions{
+			Iterator: iterator,
+		})
+		if err != nil {
+			return nil, err
+		}
+		integrations = append(integrations, out.Data...)
+		if out.Done || out.Iterator.Get() == nil {
+			return integrations, nil
+		}
+		iterator = out.Iterator.Get()
+	}
+}
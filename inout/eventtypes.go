@@ -1,102 +1,359 @@
 package inout
 
 import (
+	"bytes"
 	"context"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"sync"
 
 	svix "github.com/svix/svix-webhooks/go"
 )
 
-func ImportEventTypesJson(ctx context.Context, sc *svix.Svix, reader io.Reader, update bool) error {
+func ReadEventTypesJson(reader io.Reader) ([]*svix.EventTypeIn, error) {
 	dec := json.NewDecoder(reader)
 	var eventTypes []*svix.EventTypeIn
 	err := dec.Decode(&eventTypes)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	for _, et := range eventTypes {
-		err = createOrUpdateEventType(ctx, sc, et, update)
+	return eventTypes, nil
+}
+
+// ReadEventTypesYaml reads event types in the json format, written as yaml.
+func ReadEventTypesYaml(reader io.Reader) ([]*svix.EventTypeIn, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	return ReadEventTypesJson(bytes.NewReader(data))
+}
+
+// ReadEventTypesNdjson reads an event type per line.
+func ReadEventTypesNdjson(reader io.Reader) ([]*svix.EventTypeIn, error) {
+	var eventTypes []*svix.EventTypeIn
+	dec := json.NewDecoder(reader)
+	for {
+		var et svix.EventTypeIn
+		err := dec.Decode(&et)
+		if err == io.EOF {
+			return eventTypes, nil
+		}
 		if err != nil {
-			return err
+			return nil, err
 		}
+		eventTypes = append(eventTypes, &et)
 	}
-	return nil
 }
 
-func ImportEventTypesCsv(ctx context.Context, sc *svix.Svix, reader io.Reader, update bool) error {
+// eventTypesCsvHeader is the header row written by WriteEventTypesAsCsv.
+var eventTypesCsvHeader = []string{"name", "description", "archived", "featureFlag", "schemas"}
+
+// ReadEventTypesCsv reads name,description records, optionally followed by the
+// archived, featureFlag and schemas columns written by WriteEventTypesAsCsv. A
+// header row, as WriteEventTypesAsCsv writes, is skipped if there is one.
+func ReadEventTypesCsv(reader io.Reader) ([]*svix.EventTypeIn, error) {
+	var eventTypes []*svix.EventTypeIn
 	csvReader := csv.NewReader(reader)
-	for {
+	csvReader.FieldsPerRecord = -1
+	for line := 1; ; line++ {
 		record, err := csvReader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if len(record) < 2 {
-			return fmt.Errorf("invalid csv record")
+			return nil, fmt.Errorf("invalid csv record on line %d, expected at least a name and a description", line)
+		}
+		if line == 1 && isEventTypesCsvHeader(record) {
+			continue
 		}
 		et := &svix.EventTypeIn{
 			Name:        record[0],
 			Description: record[1],
 		}
-		err = createOrUpdateEventType(ctx, sc, et, update)
-		if err != nil {
-			return err
+		if len(record) > 2 && record[2] != "" {
+			archived, err := strconv.ParseBool(record[2])
+			if err != nil {
+				return nil, fmt.Errorf("invalid archived column for %s: %s", et.Name, record[2])
+			}
+			et.Archived = &archived
+		}
+		if len(record) > 3 && record[3] != "" {
+			featureFlag := record[3]
+			et.FeatureFlag.Set(&featureFlag)
+		}
+		if len(record) > 4 && record[4] != "" {
+			if err := json.Unmarshal([]byte(record[4]), &et.Schemas); err != nil {
+				return nil, fmt.Errorf("invalid schemas column for %s: %s", et.Name, err)
+			}
 		}
+		eventTypes = append(eventTypes, et)
 	}
-	return nil
+	return eventTypes, nil
+}
+
+func isEventTypesCsvHeader(record []string) bool {
+	for i, column := range record {
+		if i >= len(eventTypesCsvHeader) || !strings.EqualFold(column, eventTypesCsvHeader[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ImportOptions configure ImportEventTypes.
+type ImportOptions struct {
+	// Update updates the event types that already exist, instead of skipping them
+	Update bool
+	// DryRun only reports what an import would do, without changing anything
+	DryRun bool
+	// Parallelism is how many event types are imported at once
+	Parallelism int
+	// Progress is called each time an event type has been created or updated
+	Progress func(done int, total int)
 }
 
-func createOrUpdateEventType(ctx context.Context, sc *svix.Svix, et *svix.EventTypeIn, update bool) error {
-	_, err := sc.EventType.Create(ctx, et)
+// ImportEventTypes creates the event types that don't exist yet, and updates
+// the ones that do if opts.Update is set. Failures don't stop the import, they
+// are recorded in the report along with every other outcome.
+func ImportEventTypes(ctx context.Context, sc *svix.Svix, eventTypes []*svix.EventTypeIn, opts *ImportOptions) (*ImportReport, error) {
+	existing, err := GetAllEventTypes(ctx, sc)
 	if err != nil {
-		if sErr, ok := err.(*svix.Error); ok {
-			if sErr.Status() == 409 {
-				if update {
-					_, err := sc.EventType.Update(ctx, et.Name, &svix.EventTypeUpdate{Description: et.Description})
-					if err != nil {
-						return err
-					}
+		return nil, err
+	}
+	live := make(map[string]svix.EventTypeOut, len(existing))
+	for _, et := range existing {
+		live[et.Name] = et
+	}
+
+	// outcomes are decided upfront in file order, so that later duplicates of
+	// an event type are compared with what the import makes of it
+	outcomes := make([]string, len(eventTypes))
+	var jobs [][]int
+	jobOf := map[string]int{}
+	for i, et := range eventTypes {
+		liveEt, exists := live[et.Name]
+		switch {
+		case !exists:
+			outcomes[i] = OutcomeCreated
+		case !opts.Update:
+			outcomes[i] = OutcomeSkipped
+			continue
+		case eventTypeUnchanged(&liveEt, et):
+			outcomes[i] = OutcomeUnchanged
+			continue
+		default:
+			outcomes[i] = OutcomeUpdated
+		}
+		live[et.Name] = svix.EventTypeOut{
+			Archived:    et.Archived,
+			Description: et.Description,
+			FeatureFlag: et.FeatureFlag,
+			Name:        et.Name,
+			Schemas:     et.Schemas,
+		}
+		// writes to the same event type are made in order, by the same worker
+		job, ok := jobOf[et.Name]
+		if !ok {
+			job = len(jobs)
+			jobOf[et.Name] = job
+			jobs = append(jobs, nil)
+		}
+		jobs[job] = append(jobs[job], i)
+	}
+
+	errs := make([]error, len(eventTypes))
+	if !opts.DryRun {
+		total := 0
+		for _, job := range jobs {
+			total += len(job)
+		}
+		done := 0
+		var mu sync.Mutex
+		runJobs(jobs, opts.Parallelism, func(i int) {
+			errs[i] = writeEventType(ctx, sc, eventTypes[i], outcomes[i])
+			if opts.Progress != nil {
+				mu.Lock()
+				done++
+				opts.Progress(done, total)
+				mu.Unlock()
+			}
+		})
+	}
+
+	report := newImportReport(opts.DryRun)
+	for i, et := range eventTypes {
+		report.add(et.Name, outcomes[i], errs[i])
+	}
+	return report, nil
+}
+
+// runJobs calls fn with the indexes of every job, in order within a job, with
+// parallelism jobs running at once.
+func runJobs(jobs [][]int, parallelism int, fn func(i int)) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	queue := make(chan []int)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				for _, i := range job {
+					fn(i)
 				}
 			}
-		} else {
-			return err
-		}
+		}()
 	}
-	return nil
+	for _, job := range jobs {
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
 }
 
+func writeEventType(ctx context.Context, sc *svix.Svix, et *svix.EventTypeIn, outcome string) error {
+	if outcome == OutcomeCreated {
+		_, err := sc.EventType.CreateWithOptions(ctx, et, idempotentPostOptions())
+		return err
+	}
+	_, err := sc.EventType.Update(ctx, et.Name, &svix.EventTypeUpdate{
+		Archived:    et.Archived,
+		Description: et.Description,
+		FeatureFlag: et.FeatureFlag,
+		Schemas:     et.Schemas,
+	})
+	return err
+}
+
+// eventTypeUnchanged reports whether updating an event type would leave it as is.
+func eventTypeUnchanged(live *svix.EventTypeOut, et *svix.EventTypeIn) bool {
+	liveArchived := live.Archived != nil && *live.Archived
+	archived := et.Archived != nil && *et.Archived
+	return live.Description == et.Description &&
+		liveArchived == archived &&
+		stringPtrEqual(live.FeatureFlag.Get(), et.FeatureFlag.Get()) &&
+		schemasEqual(live.Schemas, et.Schemas)
+}
+
+func createOrUpdateEventType(ctx context.Context, sc *svix.Svix, et *svix.EventTypeIn, update bool) (string, error) {
+	_, err := sc.EventType.CreateWithOptions(ctx, et, idempotentPostOptions())
+	if err == nil {
+		return OutcomeCreated, nil
+	}
+	if !isConflict(err) {
+		return "", err
+	}
+	if !update {
+		return OutcomeSkipped, nil
+	}
+	_, err = sc.EventType.Update(ctx, et.Name, &svix.EventTypeUpdate{
+		Archived:    et.Archived,
+		Description: et.Description,
+		FeatureFlag: et.FeatureFlag,
+		Schemas:     et.Schemas,
+	})
+	if err != nil {
+		return "", err
+	}
+	return OutcomeUpdated, nil
+}
+
+// GetAllEventTypes lists every event type, including archived ones, with their schemas.
 func GetAllEventTypes(ctx context.Context, sc *svix.Svix) ([]svix.EventTypeOut, error) {
 	var eventTypes []svix.EventTypeOut
+	err := ForEachEventType(ctx, sc, func(et svix.EventTypeOut) error {
+		eventTypes = append(eventTypes, et)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return eventTypes, nil
+}
+
+// ForEachEventType calls fn with every event type, a page at a time, so they
+// can be streamed.
+func ForEachEventType(ctx context.Context, sc *svix.Svix, fn func(et svix.EventTypeOut) error) error {
+	withContent := true
+	includeArchived := true
 	done := false
 	var iterator *string
 	for !done {
 		out, err := sc.EventType.List(ctx, &svix.EventTypeListOptions{
-			Iterator: iterator,
+			Iterator:        iterator,
+			WithContent:     &withContent,
+			IncludeArchived: &includeArchived,
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 		for _, et := range out.Data {
-			eventTypes = append(eventTypes, svix.EventTypeOut(et))
+			if err := fn(svix.EventTypeOut(et)); err != nil {
+				return err
+			}
 		}
 		if out.Iterator.Get() != nil {
 			iterator = out.Iterator.Get()
 		}
 		done = out.Done
 	}
-	return eventTypes, nil
+	return nil
 }
 
+// ExportEventTypesNdjson streams every event type to writer, one per line.
+func ExportEventTypesNdjson(ctx context.Context, sc *svix.Svix, writer io.Writer) error {
+	enc := json.NewEncoder(writer)
+	return ForEachEventType(ctx, sc, func(et svix.EventTypeOut) error {
+		return enc.Encode(et)
+	})
+}
+
+func WriteEventTypesAsYaml(eventTypes []svix.EventTypeOut, writer io.Writer) error {
+	b, err := jsonToYAML(eventTypes)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(b)
+	return err
+}
+
+// WriteEventTypesAsCsv writes a header row, then a name,description,archived,featureFlag,schemas
+// record per event type, with the schemas as json.
 func WriteEventTypesAsCsv(eventTypes []svix.EventTypeOut, writer io.Writer) error {
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
+	if err := csvWriter.Write(eventTypesCsvHeader); err != nil {
+		return err
+	}
 	for _, et := range eventTypes {
-		err := csvWriter.Write([]string{et.Name, et.Description})
+		archived := et.Archived != nil && *et.Archived
+		featureFlag := ""
+		if et.FeatureFlag.Get() != nil {
+			featureFlag = *et.FeatureFlag.Get()
+		}
+		schemas := ""
+		if len(et.Schemas) > 0 {
+			b, err := json.Marshal(et.Schemas)
+			if err != nil {
+				return err
+			}
+			schemas = string(b)
+		}
+		err := csvWriter.Write([]string{et.Name, et.Description, strconv.FormatBool(archived), featureFlag, schemas})
 		if err != nil {
 			return err
 		}
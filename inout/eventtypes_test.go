@@ -0,0 +1,104 @@
+package inout
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func TestReadEventTypesCsvTwoColumns(t *testing.T) {
+	// the original name,description shape, without a header row
+	eventTypes, err := ReadEventTypesCsv(strings.NewReader("invoice.paid,An invoice was paid\nuser.created,\"A user, was created\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventTypes) != 2 {
+		t.Fatalf("read %d event types, want 2", len(eventTypes))
+	}
+	et := eventTypes[1]
+	if et.Name != "user.created" || et.Description != "A user, was created" {
+		t.Errorf("event type = %s, %q", et.Name, et.Description)
+	}
+	if et.Archived != nil || et.FeatureFlag.Get() != nil || et.Schemas != nil {
+		t.Errorf("event type has archived %v, feature flag %v, schemas %v, want none", et.Archived, et.FeatureFlag.Get(), et.Schemas)
+	}
+}
+
+func TestReadEventTypesCsvFiveColumns(t *testing.T) {
+	csv := `name,description,archived,featureFlag,schemas
+invoice.paid,An invoice was paid,false,beta,"{""1"":{""type"":""object""}}"
+user.deleted,A user was deleted,true,,
+user.created,A user was created
+`
+	eventTypes, err := ReadEventTypesCsv(strings.NewReader(csv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventTypes) != 3 {
+		t.Fatalf("read %d event types, want 3 without the header", len(eventTypes))
+	}
+	paid := eventTypes[0]
+	if paid.Archived == nil || *paid.Archived || paid.FeatureFlag.Get() == nil || *paid.FeatureFlag.Get() != "beta" {
+		t.Errorf("invoice.paid has archived %v, feature flag %v", paid.Archived, paid.FeatureFlag.Get())
+	}
+	if want := map[string]map[string]interface{}{"1": {"type": "object"}}; !reflect.DeepEqual(paid.Schemas, want) {
+		t.Errorf("invoice.paid schemas = %v, want %v", paid.Schemas, want)
+	}
+	deleted := eventTypes[1]
+	if deleted.Archived == nil || !*deleted.Archived || deleted.FeatureFlag.Get() != nil || deleted.Schemas != nil {
+		t.Errorf("user.deleted = %+v", deleted)
+	}
+}
+
+func TestReadEventTypesCsvInvalid(t *testing.T) {
+	for _, csv := range []string{
+		"invoice.paid\n",
+		"invoice.paid,Paid,maybe\n",
+		"invoice.paid,Paid,false,,{not json}\n",
+		"invoice.paid,\"unterminated\n",
+	} {
+		if _, err := ReadEventTypesCsv(strings.NewReader(csv)); err == nil {
+			t.Errorf("read %q, want an error", csv)
+		}
+	}
+}
+
+func TestEventTypesCsvRoundTrip(t *testing.T) {
+	archived := true
+	flag := "beta"
+	out := []svix.EventTypeOut{
+		{Name: "invoice.paid", Description: "An invoice, paid", Schemas: map[string]map[string]interface{}{"1": {"type": "object"}}},
+		{Name: "user.deleted", Description: "A \"user\" was deleted", Archived: &archived},
+	}
+	out[0].FeatureFlag.Set(&flag)
+
+	var buf bytes.Buffer
+	if err := WriteEventTypesAsCsv(out, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "name,description,archived,featureFlag,schemas\n") {
+		t.Errorf("csv doesn't start with a header row:\n%s", buf.String())
+	}
+
+	in, err := ReadEventTypesCsv(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(in) != len(out) {
+		t.Fatalf("read %d event types, want %d", len(in), len(out))
+	}
+	for i := range out {
+		if in[i].Name != out[i].Name || in[i].Description != out[i].Description || !reflect.DeepEqual(in[i].Schemas, out[i].Schemas) {
+			t.Errorf("event type %d = %+v, want %+v", i, in[i], out[i])
+		}
+		if *in[i].Archived != (out[i].Archived != nil && *out[i].Archived) {
+			t.Errorf("event type %d archived = %v", i, *in[i].Archived)
+		}
+		if !stringPtrEqual(in[i].FeatureFlag.Get(), out[i].FeatureFlag.Get()) {
+			t.Errorf("event type %d feature flag = %v, want %v", i, in[i].FeatureFlag.Get(), out[i].FeatureFlag.Get())
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package inout
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+var jsonSchemaExtensions = []string{".json", ".yaml", ".yml"}
+
+// EventTypesFromJsonSchemaDir converts a directory of JSON Schema files (in json
+// or yaml) to event types, one per file. Event types are named after their file,
+// without its extension or a .schema suffix (e.g. invoice.paid.schema.json is
+// invoice.paid), and described by the schema's description or title.
+func EventTypesFromJsonSchemaDir(dir string) ([]*svix.EventTypeIn, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var eventTypes []*svix.EventTypeIn
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || !isJsonSchemaExtension(ext) {
+			continue
+		}
+		schema, err := readJsonSchema(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", entry.Name(), err)
+		}
+		name := strings.TrimSuffix(strings.TrimSuffix(entry.Name(), ext), ".schema")
+		eventTypes = append(eventTypes, &svix.EventTypeIn{
+			Name:        name,
+			Description: schemaDescription(schema),
+			Schemas:     map[string]map[string]interface{}{"1": schema},
+		})
+	}
+	if len(eventTypes) == 0 {
+		return nil, fmt.Errorf("no JSON Schema files found in %s", dir)
+	}
+	return eventTypes, nil
+}
+
+func readJsonSchema(fileName string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("schema is not an object")
+	}
+	return schema, nil
+}
+
+func isJsonSchemaExtension(ext string) bool {
+	for _, e := range jsonSchemaExtensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+package inout
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		fileName := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(fileName), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fileName, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestEventTypesFromJsonSchemaDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"invoice.paid.json":        `{"description": "An invoice was paid", "type": "object"}`,
+		"user.created.schema.yaml": "title: A user was created\ntype: object\n",
+		"user.deleted.YML":         "type: object\n",
+		"README.md":                "not a schema",
+		"nested/ignored.json":      `{"type": "object"}`,
+	})
+	eventTypes, err := EventTypesFromJsonSchemaDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		name        string
+		description string
+	}{
+		{name: "invoice.paid", description: "An invoice was paid"},
+		{name: "user.created", description: "A user was created"},
+		{name: "user.deleted", description: ""},
+	}
+	if len(eventTypes) != len(want) {
+		t.Fatalf("converted %d event types, want %d", len(eventTypes), len(want))
+	}
+	for i, w := range want {
+		et := eventTypes[i]
+		if et.Name != w.name || et.Description != w.description {
+			t.Errorf("event type %d = %s, %q, want %s, %q", i, et.Name, et.Description, w.name, w.description)
+		}
+		if et.Schemas["1"]["type"] != "object" {
+			t.Errorf("%s schema = %v", et.Name, et.Schemas)
+		}
+	}
+}
+
+func TestEventTypesFromJsonSchemaDirInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+	}{
+		{name: "no schemas", files: map[string]string{"README.md": "x"}},
+		{name: "not an object", files: map[string]string{"a.json": `["type", "object"]`}},
+		{name: "invalid yaml", files: map[string]string{"a.yaml": "type: ["}},
+	}
+	for _, tt := range tests {
+		if _, err := EventTypesFromJsonSchemaDir(writeFiles(t, tt.files)); err == nil {
+			t.Errorf("%s: converted, want an error", tt.name)
+		}
+	}
+	if _, err := EventTypesFromJsonSchemaDir(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("converted a missing directory, want an error")
+	}
+}
@@ -0,0 +1,160 @@
+package inout
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// MessageExportOptions select the messages of an application that are exported.
+type MessageExportOptions struct {
+	// Since and Until bound the time window of the messages, if set
+	Since *time.Time
+	Until *time.Time
+	// EventTypes only exports messages of these event types, if not empty
+	EventTypes []string
+	// WithAttempts exports the delivery attempts of every message along with it
+	WithAttempts bool
+}
+
+// ExportedMessage is a message with its payload and, if they were exported, its
+// delivery attempts.
+type ExportedMessage struct {
+	Id        string                    `json:"id"`
+	EventType string                    `json:"eventType"`
+	EventId   *string                   `json:"eventId,omitempty"`
+	Channels  []string                  `json:"channels,omitempty"`
+	Timestamp time.Time                 `json:"timestamp"`
+	Payload   map[string]interface{}    `json:"payload"`
+	Attempts  *[]svix.MessageAttemptOut `json:"attempts,omitempty"`
+}
+
+// ForEachMessage calls fn with every message of an application that matches
+// opts, in the order they are listed, fetching them a page at a time.
+func ForEachMessage(ctx context.Context, sc *svix.Svix, appID string, opts *MessageExportOptions, fn func(msg *ExportedMessage) error) error {
+	listOpts := &svix.MessageListOptions{
+		Before: opts.Until,
+		After:  opts.Since,
+	}
+	if len(opts.EventTypes) > 0 {
+		listOpts.EventTypes = &opts.EventTypes
+	}
+	done := false
+	for !done {
+		out, err := sc.Message.List(ctx, appID, listOpts)
+		if err != nil {
+			return err
+		}
+		for _, msg := range out.Data {
+			exported := &ExportedMessage{
+				Id:        msg.Id,
+				EventType: msg.EventType,
+				EventId:   msg.EventId.Get(),
+				Channels:  msg.Channels,
+				Timestamp: msg.Timestamp,
+				Payload:   msg.Payload,
+			}
+			if opts.WithAttempts {
+				attempts, err := getAllAttempts(ctx, sc, appID, msg.Id)
+				if err != nil {
+					return err
+				}
+				exported.Attempts = &attempts
+			}
+			if err := fn(exported); err != nil {
+				return err
+			}
+		}
+		if out.Iterator.Get() != nil {
+			listOpts.Iterator = out.Iterator.Get()
+		}
+		done = out.Done
+	}
+	return nil
+}
+
+func getAllAttempts(ctx context.Context, sc *svix.Svix, appID string, msgID string) ([]svix.MessageAttemptOut, error) {
+	attempts := []svix.MessageAttemptOut{}
+	done := false
+	var iterator *string
+	for !done {
+		out, err := sc.MessageAttempt.ListByMsg(ctx, appID, msgID, &svix.MessageAttemptListOptions{
+			Iterator: iterator,
+		})
+		if err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, out.Data...)
+		if out.Iterator.Get() != nil {
+			iterator = out.Iterator.Get()
+		}
+		done = out.Done
+	}
+	return attempts, nil
+}
+
+// ExportMessagesNdjson streams the messages of an application to writer, one
+// per line, and returns how many were exported.
+func ExportMessagesNdjson(ctx context.Context, sc *svix.Svix, appID string, opts *MessageExportOptions, writer io.Writer) (int, error) {
+	count := 0
+	enc := json.NewEncoder(writer)
+	err := ForEachMessage(ctx, sc, appID, opts, func(msg *ExportedMessage) error {
+		count++
+		return enc.Encode(msg)
+	})
+	return count, err
+}
+
+// ExportMessagesCsv streams the messages of an application to writer as
+// id,eventType,eventId,channels,timestamp,payload,attempts records, with the
+// channels, payload and attempts as json, and returns how many were exported.
+func ExportMessagesCsv(ctx context.Context, sc *svix.Svix, appID string, opts *MessageExportOptions, writer io.Writer) (int, error) {
+	count := 0
+	csvWriter := csv.NewWriter(writer)
+	if err := csvWriter.Write([]string{"id", "eventType", "eventId", "channels", "timestamp", "payload", "attempts"}); err != nil {
+		return 0, err
+	}
+	err := ForEachMessage(ctx, sc, appID, opts, func(msg *ExportedMessage) error {
+		eventID := ""
+		if msg.EventId != nil {
+			eventID = *msg.EventId
+		}
+		channels, err := jsonOrEmpty(msg.Channels, len(msg.Channels) > 0)
+		if err != nil {
+			return err
+		}
+		payload, err := json.Marshal(msg.Payload)
+		if err != nil {
+			return err
+		}
+		attempts, err := jsonOrEmpty(msg.Attempts, msg.Attempts != nil)
+		if err != nil {
+			return err
+		}
+		count++
+		record := []string{msg.Id, msg.EventType, eventID, channels, msg.Timestamp.Format(time.RFC3339Nano), string(payload), attempts}
+		if err := csvWriter.Write(record); err != nil {
+			return err
+		}
+		// flush every message, so an export that fails halfway keeps what it got
+		csvWriter.Flush()
+		return csvWriter.Error()
+	})
+	csvWriter.Flush()
+	if err == nil {
+		err = csvWriter.Error()
+	}
+	return count, err
+}
+
+func jsonOrEmpty(v interface{}, include bool) (string, error) {
+	if !include {
+		return "", nil
+	}
+	b, err := json.Marshal(v)
+	return string(b), err
+}
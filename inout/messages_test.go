@@ -0,0 +1,215 @@
+package inout
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// pagedServer serves message and attempt listings a page at a time: a page is
+// found by the iterator of the request, and points at the next one.
+type pagedServer struct {
+	messages map[string]listPage
+	attempts map[string]listPage
+
+	mu       sync.Mutex
+	requests []string
+}
+
+type listPage struct {
+	Data     []interface{} `json:"data"`
+	Iterator *string       `json:"iterator"`
+	Done     bool          `json:"done"`
+}
+
+func (s *pagedServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.RequestURI())
+	s.mu.Unlock()
+
+	pages := s.messages
+	key := r.URL.Query().Get("iterator")
+	if msgID := strings.TrimPrefix(r.URL.Path, "/api/v1/app/app_1/attempt/msg/"); msgID != r.URL.Path {
+		pages = s.attempts
+		key = strings.TrimSuffix(msgID, "/") + "@" + key
+	} else if r.URL.Path != "/api/v1/app/app_1/msg/" {
+		http.NotFound(w, r)
+		return
+	}
+	page, ok := pages[key]
+	if !ok {
+		http.Error(w, `{"code":"not_found","detail":"unknown iterator"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func testMessage(id string, eventType string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        id,
+		"eventType": eventType,
+		"timestamp": "2023-09-01T10:00:00Z",
+		"payload":   map[string]interface{}{"id": id},
+	}
+}
+
+func testAttempt(id string, msgID string) map[string]interface{} {
+	return map[string]interface{}{
+		"id":                 id,
+		"msgId":              msgID,
+		"endpointId":         "ep_1",
+		"url":                "https://example.com/webhook",
+		"response":           "ok",
+		"responseStatusCode": 200,
+		"status":             0,
+		"triggerType":        0,
+		"timestamp":          "2023-09-01T10:00:01Z",
+	}
+}
+
+func newPagedServer(t *testing.T) (*pagedServer, *svix.Svix) {
+	t.Helper()
+	it1, it2, attemptIt := "it_1", "it_2", "attempt_it_1"
+	server := &pagedServer{
+		messages: map[string]listPage{
+			"":     {Data: []interface{}{testMessage("msg_1", "invoice.paid"), testMessage("msg_2", "invoice.voided")}, Iterator: &it1},
+			"it_1": {Data: []interface{}{testMessage("msg_3", "invoice.paid")}, Iterator: &it2},
+			// the last page still has an iterator, done is what ends the listing
+			"it_2": {Data: []interface{}{testMessage("msg_4", "user.created")}, Iterator: &it2, Done: true},
+		},
+		attempts: map[string]listPage{
+			"msg_1@":             {Data: []interface{}{testAttempt("atmpt_1", "msg_1")}, Iterator: &attemptIt},
+			"msg_1@attempt_it_1": {Data: []interface{}{testAttempt("atmpt_2", "msg_1")}, Done: true},
+			"msg_2@":             {Data: []interface{}{}, Done: true},
+			"msg_3@":             {Data: []interface{}{testAttempt("atmpt_3", "msg_3")}, Done: true},
+			"msg_4@":             {Data: []interface{}{}, Done: true},
+		},
+	}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	serverURL, _ := url.Parse(ts.URL)
+	return server, svix.New("testsk_test", &svix.SvixOptions{ServerUrl: serverURL})
+}
+
+func TestForEachMessagePages(t *testing.T) {
+	server, sc := newPagedServer(t)
+	since := time.Date(2023, 9, 1, 0, 0, 0, 0, time.UTC)
+	opts := &MessageExportOptions{Since: &since, EventTypes: []string{"invoice.paid", "user.created"}, WithAttempts: true}
+
+	var ids []string
+	attempts := map[string][]string{}
+	err := ForEachMessage(context.Background(), sc, "app_1", opts, func(msg *ExportedMessage) error {
+		ids = append(ids, msg.Id)
+		if msg.Attempts == nil {
+			t.Errorf("%s has no attempts, want them exported", msg.Id)
+			return nil
+		}
+		attempts[msg.Id] = []string{}
+		for _, attempt := range *msg.Attempts {
+			attempts[msg.Id] = append(attempts[msg.Id], attempt.Id)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"msg_1", "msg_2", "msg_3", "msg_4"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("messages = %v, want %v", ids, want)
+	}
+	wantAttempts := map[string][]string{"msg_1": {"atmpt_1", "atmpt_2"}, "msg_2": {}, "msg_3": {"atmpt_3"}, "msg_4": {}}
+	if !reflect.DeepEqual(attempts, wantAttempts) {
+		t.Errorf("attempts = %v, want %v", attempts, wantAttempts)
+	}
+
+	var listRequests []string
+	for _, request := range server.requests {
+		if strings.HasPrefix(request, "/api/v1/app/app_1/msg/") {
+			listRequests = append(listRequests, request)
+		}
+	}
+	if len(listRequests) != 3 {
+		t.Fatalf("listed %d message pages, want 3: %v", len(listRequests), listRequests)
+	}
+	for i, request := range listRequests {
+		query, _ := url.ParseQuery(strings.SplitN(request, "?", 2)[1])
+		if query.Get("after") != "2023-09-01T00:00:00Z" || len(query["event_types"]) != 2 {
+			t.Errorf("page %d was listed without the export options: %s", i, request)
+		}
+		if want := []string{"", "it_1", "it_2"}[i]; query.Get("iterator") != want {
+			t.Errorf("page %d was listed with iterator %q, want %q", i, query.Get("iterator"), want)
+		}
+	}
+}
+
+func TestForEachMessageStopsOnError(t *testing.T) {
+	_, sc := newPagedServer(t)
+	stop := errors.New("stop")
+	count := 0
+	err := ForEachMessage(context.Background(), sc, "app_1", &MessageExportOptions{}, func(msg *ExportedMessage) error {
+		count++
+		if msg.Id == "msg_2" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || count != 2 {
+		t.Errorf("ForEachMessage returned %v after %d messages, want stop after 2", err, count)
+	}
+
+	err = ForEachMessage(context.Background(), sc, "app_2", &MessageExportOptions{}, func(msg *ExportedMessage) error {
+		return nil
+	})
+	if err == nil {
+		t.Error("listed the messages of an unknown application, want an error")
+	}
+}
+
+func TestExportMessagesCsv(t *testing.T) {
+	_, sc := newPagedServer(t)
+	var buf bytes.Buffer
+	count, err := ExportMessagesCsv(context.Background(), sc, "app_1", &MessageExportOptions{WithAttempts: true}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("exported %d messages, want 4", count)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("wrote %d records, want a header and 4 messages", len(records))
+	}
+	if want := []string{"id", "eventType", "eventId", "channels", "timestamp", "payload", "attempts"}; !reflect.DeepEqual(records[0], want) {
+		t.Errorf("header = %v, want %v", records[0], want)
+	}
+	first := records[1]
+	if first[0] != "msg_1" || first[1] != "invoice.paid" || first[2] != "" || first[3] != "" || first[4] != "2023-09-01T10:00:00Z" || first[5] != `{"id":"msg_1"}` {
+		t.Errorf("first record = %v", first)
+	}
+	var attempts []svix.MessageAttemptOut
+	if err := json.Unmarshal([]byte(first[6]), &attempts); err != nil {
+		t.Fatal(err)
+	}
+	if len(attempts) != 2 || attempts[0].Id != "atmpt_1" || attempts[1].Id != "atmpt_2" {
+		t.Errorf("first record attempts = %s", first[6])
+	}
+	if records[2][6] != "[]" {
+		t.Errorf("second record attempts = %q, want an empty list", records[2][6])
+	}
+}
@@ -0,0 +1,230 @@
+package inout
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// webhookMethods are the operations of a webhook looked at, in order, for its
+// description and payload schema.
+var webhookMethods = []string{"post", "put", "patch", "get", "delete"}
+
+// EventTypesFromOpenApi converts an OpenAPI 3 document, in yaml or json, to event
+// types. Each webhook in the document's webhooks section becomes an event type
+// named after it, with the description and request body schema of its operation.
+// Deprecated webhooks are archived, and x-svix-feature-flag sets the feature flag.
+// Documents without webhooks get an event type per components.schemas entry
+// instead. Local references ($ref: "#/...") are inlined in the schemas.
+func EventTypesFromOpenApi(reader io.Reader) ([]*svix.EventTypeIn, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %s", err)
+	}
+	if version, _ := doc["openapi"].(string); !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("not an OpenAPI 3 document")
+	}
+	resolver := &refResolver{doc: doc, resolving: map[string]bool{}}
+
+	var eventTypes []*svix.EventTypeIn
+	webhooks, _ := doc["webhooks"].(map[string]interface{})
+	if len(webhooks) > 0 {
+		for _, name := range sortedKeys(webhooks) {
+			et, err := webhookEventType(resolver, name, webhooks[name])
+			if err != nil {
+				return nil, fmt.Errorf("webhook %s: %s", name, err)
+			}
+			eventTypes = append(eventTypes, et)
+		}
+		return eventTypes, nil
+	}
+
+	components, _ := doc["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	if len(schemas) == 0 {
+		return nil, fmt.Errorf("OpenAPI document has no webhooks or components.schemas")
+	}
+	for _, name := range sortedKeys(schemas) {
+		schema, err := resolver.resolve(schemas[name])
+		if err != nil {
+			return nil, fmt.Errorf("schema %s: %s", name, err)
+		}
+		schemaMap, ok := schema.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("schema %s is not an object", name)
+		}
+		eventTypes = append(eventTypes, &svix.EventTypeIn{
+			Name:        name,
+			Description: schemaDescription(schemaMap),
+			Schemas:     map[string]map[string]interface{}{"1": schemaMap},
+		})
+	}
+	return eventTypes, nil
+}
+
+func webhookEventType(resolver *refResolver, name string, pathItem interface{}) (*svix.EventTypeIn, error) {
+	resolved, err := resolver.resolve(pathItem)
+	if err != nil {
+		return nil, err
+	}
+	item, _ := resolved.(map[string]interface{})
+	var op map[string]interface{}
+	for _, method := range webhookMethods {
+		if op, _ = item[method].(map[string]interface{}); op != nil {
+			break
+		}
+	}
+	if op == nil {
+		return nil, fmt.Errorf("no operation found")
+	}
+
+	et := &svix.EventTypeIn{Name: name}
+	if et.Description, _ = op["description"].(string); et.Description == "" {
+		et.Description, _ = op["summary"].(string)
+	}
+	if deprecated, _ := op["deprecated"].(bool); deprecated {
+		et.Archived = &deprecated
+	}
+	if featureFlag, _ := op["x-svix-feature-flag"].(string); featureFlag != "" {
+		et.FeatureFlag.Set(&featureFlag)
+	}
+	requestBody, _ := op["requestBody"].(map[string]interface{})
+	content, _ := requestBody["content"].(map[string]interface{})
+	for _, mediaType := range sortedKeys(content) {
+		if !strings.Contains(mediaType, "json") {
+			continue
+		}
+		media, _ := content[mediaType].(map[string]interface{})
+		if schema, ok := media["schema"].(map[string]interface{}); ok {
+			et.Schemas = map[string]map[string]interface{}{"1": schema}
+			break
+		}
+	}
+	return et, nil
+}
+
+// refResolver inlines the local references of an OpenAPI document.
+type refResolver struct {
+	doc       map[string]interface{}
+	resolving map[string]bool
+}
+
+func (r *refResolver) resolve(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok {
+			return r.resolveRef(ref, v)
+		}
+		out := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			resolved, err := r.resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, value := range v {
+			resolved, err := r.resolve(value)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// resolveRef inlines a reference, keeping the keys next to it (e.g. a
+// description) over the referenced ones.
+func (r *refResolver) resolveRef(ref string, v map[string]interface{}) (interface{}, error) {
+	if r.resolving[ref] {
+		return nil, fmt.Errorf("recursive reference %s can't be inlined", ref)
+	}
+	target, err := lookupPointer(r.doc, ref)
+	if err != nil {
+		return nil, err
+	}
+	r.resolving[ref] = true
+	resolved, err := r.resolve(target)
+	delete(r.resolving, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvedMap, ok := resolved.(map[string]interface{})
+	if !ok || len(v) == 1 {
+		return resolved, nil
+	}
+	for key, value := range v {
+		if key == "$ref" {
+			continue
+		}
+		if resolvedMap[key], err = r.resolve(value); err != nil {
+			return nil, err
+		}
+	}
+	return resolvedMap, nil
+}
+
+// lookupPointer finds the value a local reference (a JSON pointer such as
+// #/components/schemas/Invoice) points to.
+func lookupPointer(doc map[string]interface{}, ref string) (interface{}, error) {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil, fmt.Errorf("reference %s isn't supported, only local references are", ref)
+	}
+	var v interface{} = doc
+	for _, token := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch node := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = node[token]; !ok {
+				return nil, fmt.Errorf("reference %s not found", ref)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, fmt.Errorf("reference %s not found", ref)
+			}
+			v = node[i]
+		default:
+			return nil, fmt.Errorf("reference %s not found", ref)
+		}
+	}
+	return v, nil
+}
+
+func schemaDescription(schema map[string]interface{}) string {
+	if description, _ := schema["description"].(string); description != "" {
+		return description
+	}
+	title, _ := schema["title"].(string)
+	return title
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
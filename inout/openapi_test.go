@@ -0,0 +1,130 @@
+package inout
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEventTypesFromOpenApiWebhooks(t *testing.T) {
+	doc := `
+openapi: 3.1.0
+info: {title: Billing, version: "1"}
+webhooks:
+  invoice.paid:
+    post:
+      summary: An invoice was paid
+      x-svix-feature-flag: beta
+      requestBody:
+        content:
+          application/json:
+            schema: {$ref: "#/components/schemas/Invoice"}
+  invoice.voided:
+    post:
+      description: An invoice was voided
+      deprecated: true
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: "#/components/schemas/Invoice"
+              description: The voided invoice
+  ping:
+    put:
+      summary: Ping
+components:
+  schemas:
+    Invoice:
+      type: object
+      properties:
+        id: {type: string}
+        customer: {$ref: "#/components/schemas/Customer"}
+    Customer:
+      type: object
+      properties:
+        name: {type: string}
+`
+	eventTypes, err := EventTypesFromOpenApi(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventTypes) != 3 {
+		t.Fatalf("converted %d event types, want 3", len(eventTypes))
+	}
+
+	paid, voided, ping := eventTypes[0], eventTypes[1], eventTypes[2]
+	if paid.Name != "invoice.paid" || paid.Description != "An invoice was paid" || paid.Archived != nil {
+		t.Errorf("invoice.paid = %+v", paid)
+	}
+	if flag := paid.FeatureFlag.Get(); flag == nil || *flag != "beta" {
+		t.Errorf("invoice.paid feature flag = %v, want beta", flag)
+	}
+	wantSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{"type": "string"},
+			"customer": map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+			},
+		},
+	}
+	if !reflect.DeepEqual(paid.Schemas["1"], wantSchema) {
+		t.Errorf("invoice.paid schema = %v, want the references inlined: %v", paid.Schemas["1"], wantSchema)
+	}
+
+	if voided.Description != "An invoice was voided" || voided.Archived == nil || !*voided.Archived {
+		t.Errorf("invoice.voided = %+v, want it archived", voided)
+	}
+	if description := voided.Schemas["1"]["description"]; description != "The voided invoice" {
+		t.Errorf("invoice.voided schema description = %v, want the one next to the reference", description)
+	}
+	if ping.Name != "ping" || ping.Description != "Ping" || ping.Schemas != nil {
+		t.Errorf("ping = %+v, want no schema", ping)
+	}
+}
+
+func TestEventTypesFromOpenApiComponents(t *testing.T) {
+	doc := `{
+		"openapi": "3.0.3",
+		"components": {"schemas": {
+			"user.created": {"title": "A user was created", "type": "object"},
+			"invoice.paid": {"description": "An invoice was paid", "title": "Paid", "type": "object"}
+		}}
+	}`
+	eventTypes, err := EventTypesFromOpenApi(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(eventTypes) != 2 {
+		t.Fatalf("converted %d event types, want 2", len(eventTypes))
+	}
+	if eventTypes[0].Name != "invoice.paid" || eventTypes[0].Description != "An invoice was paid" {
+		t.Errorf("first event type = %+v, want invoice.paid described by its description", eventTypes[0])
+	}
+	if eventTypes[1].Name != "user.created" || eventTypes[1].Description != "A user was created" {
+		t.Errorf("second event type = %+v, want user.created described by its title", eventTypes[1])
+	}
+}
+
+func TestEventTypesFromOpenApiInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		doc     string
+		wantErr string
+	}{
+		{name: "swagger 2", doc: `{"swagger": "2.0"}`, wantErr: "not an OpenAPI 3 document"},
+		{name: "not yaml", doc: "openapi: [", wantErr: ""},
+		{name: "nothing to convert", doc: `{"openapi": "3.0.0", "paths": {}}`, wantErr: "no webhooks"},
+		{name: "missing reference", doc: `{"openapi": "3.0.0", "components": {"schemas": {"a": {"$ref": "#/components/schemas/b"}}}}`, wantErr: "not found"},
+		{name: "remote reference", doc: `{"openapi": "3.0.0", "components": {"schemas": {"a": {"$ref": "other.yaml#/a"}}}}`, wantErr: "only local references"},
+		{name: "recursive reference", doc: `{"openapi": "3.0.0", "components": {"schemas": {"a": {"properties": {"child": {"$ref": "#/components/schemas/a"}}}}}}`, wantErr: "recursive"},
+		{name: "webhook without operation", doc: `{"openapi": "3.1.0", "webhooks": {"a": {"summary": "x"}}}`, wantErr: "no operation"},
+	}
+	for _, tt := range tests {
+		_, err := EventTypesFromOpenApi(strings.NewReader(tt.doc))
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package inout
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+const (
+	ChangeCreate = "create"
+	ChangeUpdate = "update"
+	ChangeDelete = "delete"
+)
+
+// Change is a single change needed to bring the organization to the desired
+// state, e.g. creating an endpoint.
+type Change struct {
+	Action string
+	// Kind is event-type, application or endpoint
+	Kind string
+	Name string
+	// Fields are the fields an update changes
+	Fields []string
+
+	apply func(ctx context.Context, sc *svix.Svix) error
+}
+
+func (c *Change) String() string {
+	switch c.Action {
+	case ChangeCreate:
+		return fmt.Sprintf("+ %s %s", c.Kind, c.Name)
+	case ChangeDelete:
+		return fmt.Sprintf("- %s %s", c.Kind, c.Name)
+	default:
+		return fmt.Sprintf("~ %s %s (%s)", c.Kind, c.Name, strings.Join(c.Fields, ", "))
+	}
+}
+
+func (c *Change) Apply(ctx context.Context, sc *svix.Svix) error {
+	return c.apply(ctx, sc)
+}
+
+// Plan compares the desired state with the organization, returning the changes
+// to apply, in the order they must be applied. Event types, and the endpoints of
+// the applications in the state, that aren't in the state are only deleted if
+// prune is set. Applications are never deleted.
+func Plan(ctx context.Context, sc *svix.Svix, state *State, prune bool) ([]*Change, error) {
+	var changes []*Change
+
+	liveEventTypes, err := GetAllEventTypes(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	eventTypes := make(map[string]svix.EventTypeOut, len(liveEventTypes))
+	for _, et := range liveEventTypes {
+		eventTypes[et.Name] = et
+	}
+	for i := range state.EventTypes {
+		desired := &state.EventTypes[i]
+		if change := planEventType(desired, eventTypes); change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	liveApps, err := getAllApplications(ctx, sc)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool, len(liveApps))
+	for i := range state.Applications {
+		desired := &state.Applications[i]
+		app := matchStateApplication(desired, liveApps, matched)
+		if app != nil {
+			matched[app.Id] = true
+		}
+		appChanges, err := planApplication(ctx, sc, desired, app, prune)
+		if err != nil {
+			return nil, fmt.Errorf("application %s: %s", desired.Uid, err)
+		}
+		changes = append(changes, appChanges...)
+	}
+
+	// event types are deleted last, once no endpoint filters on them
+	if prune {
+		desired := make(map[string]bool, len(state.EventTypes))
+		for _, et := range state.EventTypes {
+			desired[et.Name] = true
+		}
+		for _, et := range liveEventTypes {
+			if desired[et.Name] || (et.Archived != nil && *et.Archived) {
+				continue
+			}
+			name := et.Name
+			changes = append(changes, &Change{
+				Action: ChangeDelete,
+				Kind:   "event-type",
+				Name:   name,
+				apply: func(ctx context.Context, sc *svix.Svix) error {
+					return sc.EventType.Delete(ctx, name)
+				},
+			})
+		}
+	}
+	return changes, nil
+}
+
+func planEventType(desired *StateEventType, live map[string]svix.EventTypeOut) *Change {
+	var featureFlag *string
+	if desired.FeatureFlag != "" {
+		featureFlag = &desired.FeatureFlag
+	}
+	archived := desired.Archived
+	in := &svix.EventTypeIn{
+		Archived:    &archived,
+		Description: desired.Description,
+		Name:        desired.Name,
+		Schemas:     desired.Schemas,
+	}
+	// set even when nil, so updates clear a feature flag removed from the state
+	in.FeatureFlag.Set(featureFlag)
+	apply := func(ctx context.Context, sc *svix.Svix) error {
+		_, err := createOrUpdateEventType(ctx, sc, in, true)
+		return err
+	}
+
+	et, ok := live[desired.Name]
+	if !ok {
+		return &Change{Action: ChangeCreate, Kind: "event-type", Name: desired.Name, apply: apply}
+	}
+	var fields []string
+	if et.Description != desired.Description {
+		fields = append(fields, "description")
+	}
+	if !schemasEqual(et.Schemas, desired.Schemas) {
+		fields = append(fields, "schemas")
+	}
+	if !stringPtrEqual(et.FeatureFlag.Get(), featureFlag) {
+		fields = append(fields, "featureFlag")
+	}
+	if (et.Archived != nil && *et.Archived) != desired.Archived {
+		fields = append(fields, "archived")
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &Change{Action: ChangeUpdate, Kind: "event-type", Name: desired.Name, Fields: fields, apply: apply}
+}
+
+// matchStateApplication finds the live application with the desired one's uid
+// or, failing that, the first one not matched yet without a uid and with its name.
+func matchStateApplication(desired *StateApplication, live []svix.ApplicationOut, matched map[string]bool) *svix.ApplicationOut {
+	var byName *svix.ApplicationOut
+	for i := range live {
+		app := &live[i]
+		if matched[app.Id] {
+			continue
+		}
+		uid := app.Uid.Get()
+		if uid != nil && *uid == desired.Uid {
+			return app
+		}
+		if uid == nil && byName == nil && app.Name == desired.Name {
+			byName = app
+		}
+	}
+	return byName
+}
+
+// planApplication plans the changes to the desired application and its
+// endpoints, app is the live application it matched, if any.
+func planApplication(ctx context.Context, sc *svix.Svix, desired *StateApplication, app *svix.ApplicationOut, prune bool) ([]*Change, error) {
+	var changes []*Change
+
+	in := &svix.ApplicationIn{Name: desired.Name}
+	in.Uid.Set(&desired.Uid)
+	in.RateLimit.Set(desired.RateLimit)
+	in.Metadata = metadataOrEmpty(desired.Metadata)
+
+	if app == nil {
+		changes = append(changes, &Change{
+			Action: ChangeCreate,
+			Kind:   "application",
+			Name:   desired.Uid,
+			apply: func(ctx context.Context, sc *svix.Svix) error {
+				_, err := sc.Application.Create(ctx, in)
+				return err
+			},
+		})
+		for i := range desired.Endpoints {
+			changes = append(changes, planEndpointCreate(desired.Uid, desired.Uid, &desired.Endpoints[i]))
+		}
+		return changes, nil
+	}
+
+	// the live application is referred to by id, as an adopted one has no uid yet
+	appID := app.Id
+	var fields []string
+	if app.Uid.Get() == nil {
+		fields = append(fields, "uid")
+	}
+	if app.Name != desired.Name {
+		fields = append(fields, "name")
+	}
+	if !int32PtrEqual(app.RateLimit.Get(), desired.RateLimit) {
+		fields = append(fields, "rateLimit")
+	}
+	if !stringMapsEqual(app.Metadata, desired.Metadata) {
+		fields = append(fields, "metadata")
+	}
+	if len(fields) > 0 {
+		changes = append(changes, &Change{
+			Action: ChangeUpdate,
+			Kind:   "application",
+			Name:   desired.Uid,
+			Fields: fields,
+			apply: func(ctx context.Context, sc *svix.Svix) error {
+				_, err := sc.Application.Update(ctx, appID, in)
+				return err
+			},
+		})
+	}
+
+	liveEndpoints, err := getAllEndpoints(ctx, sc, appID)
+	if err != nil {
+		return nil, err
+	}
+	matched := make(map[string]bool, len(liveEndpoints))
+	for i := range desired.Endpoints {
+		ep := &desired.Endpoints[i]
+		liveEp := matchEndpoint(ep, liveEndpoints, matched)
+		if liveEp == nil {
+			changes = append(changes, planEndpointCreate(desired.Uid, appID, ep))
+			continue
+		}
+		matched[liveEp.Id] = true
+		change, err := planEndpointUpdate(ctx, sc, desired.Uid, appID, ep, liveEp)
+		if err != nil {
+			return nil, fmt.Errorf("endpoint %s: %s", liveEp.Id, err)
+		}
+		if change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	if prune {
+		for _, ep := range liveEndpoints {
+			if matched[ep.Id] {
+				continue
+			}
+			name := desired.Uid + "/" + ep.Id
+			if uid := ep.Uid.Get(); uid != nil {
+				name = desired.Uid + "/" + *uid
+			}
+			epID := ep.Id
+			changes = append(changes, &Change{
+				Action: ChangeDelete,
+				Kind:   "endpoint",
+				Name:   fmt.Sprintf("%s (%s)", name, ep.Url),
+				apply: func(ctx context.Context, sc *svix.Svix) error {
+					return sc.Endpoint.Delete(ctx, appID, epID)
+				},
+			})
+		}
+	}
+	return changes, nil
+}
+
+// matchEndpoint finds the live endpoint with the desired endpoint's uid, or
+// the first endpoint not matched yet with its url if it has no uid.
+func matchEndpoint(desired *StateEndpoint, live []svix.EndpointOut, matched map[string]bool) *svix.EndpointOut {
+	for i := range live {
+		ep := &live[i]
+		if matched[ep.Id] {
+			continue
+		}
+		uid := ep.Uid.Get()
+		if desired.Uid != "" && uid != nil && *uid == desired.Uid {
+			return ep
+		}
+		if desired.Uid == "" && uid == nil && ep.Url == desired.Url {
+			return ep
+		}
+	}
+	return nil
+}
+
+// planEndpointCreate plans creating an endpoint in the application with appID,
+// which is its uid when the application is created by the same apply.
+func planEndpointCreate(appUID string, appID string, desired *StateEndpoint) *Change {
+	return &Change{
+		Action: ChangeCreate,
+		Kind:   "endpoint",
+		Name:   appUID + "/" + desired.key(),
+		apply: func(ctx context.Context, sc *svix.Svix) error {
+			in := &svix.EndpointIn{
+				Channels:    desired.Channels,
+				Disabled:    &desired.Disabled,
+				FilterTypes: desired.FilterTypes,
+				Metadata:    metadataOrEmpty(desired.Metadata),
+				Url:         desired.Url,
+			}
+			in.RateLimit.Set(desired.RateLimit)
+			if desired.Uid != "" {
+				in.Uid.Set(&desired.Uid)
+			}
+			if desired.Description != "" {
+				in.Description = &desired.Description
+			}
+			out, err := sc.Endpoint.Create(ctx, appID, in)
+			if err != nil {
+				return err
+			}
+			if len(desired.Headers) > 0 {
+				return sc.Endpoint.UpdateHeaders(ctx, appID, out.Id, &svix.EndpointHeadersIn{Headers: desired.Headers})
+			}
+			return nil
+		},
+	}
+}
+
+func planEndpointUpdate(ctx context.Context, sc *svix.Svix, appUID string, appID string, desired *StateEndpoint, live *svix.EndpointOut) (*Change, error) {
+	var fields []string
+	if live.Url != desired.Url {
+		fields = append(fields, "url")
+	}
+	if live.Description != desired.Description {
+		fields = append(fields, "description")
+	}
+	if !stringSetsEqual(live.FilterTypes, desired.FilterTypes) {
+		fields = append(fields, "filterTypes")
+	}
+	if !stringSetsEqual(live.Channels, desired.Channels) {
+		fields = append(fields, "channels")
+	}
+	if !int32PtrEqual(live.RateLimit.Get(), desired.RateLimit) {
+		fields = append(fields, "rateLimit")
+	}
+	if (live.Disabled != nil && *live.Disabled) != desired.Disabled {
+		fields = append(fields, "disabled")
+	}
+	if !stringMapsEqual(live.Metadata, desired.Metadata) {
+		fields = append(fields, "metadata")
+	}
+	updateEndpoint := len(fields) > 0
+
+	updateHeaders := false
+	if desired.Headers != nil {
+		headers, err := sc.Endpoint.GetHeaders(ctx, appID, live.Id)
+		if err != nil {
+			return nil, err
+		}
+		if !headersEqual(headers, desired.Headers) {
+			fields = append(fields, "headers")
+			updateHeaders = true
+		}
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	epID := live.Id
+	return &Change{
+		Action: ChangeUpdate,
+		Kind:   "endpoint",
+		Name:   appUID + "/" + desired.key(),
+		Fields: fields,
+		apply: func(ctx context.Context, sc *svix.Svix) error {
+			if updateEndpoint {
+				update := &svix.EndpointUpdate{
+					Channels:    desired.Channels,
+					Disabled:    &desired.Disabled,
+					FilterTypes: desired.FilterTypes,
+					Metadata:    metadataOrEmpty(desired.Metadata),
+					Url:         desired.Url,
+				}
+				update.RateLimit.Set(desired.RateLimit)
+				if desired.Uid != "" {
+					update.Uid.Set(&desired.Uid)
+				}
+				description := desired.Description
+				update.Description = &description
+				if _, err := sc.Endpoint.Update(ctx, appID, epID, update); err != nil {
+					return err
+				}
+			}
+			if updateHeaders {
+				return sc.Endpoint.UpdateHeaders(ctx, appID, epID, &svix.EndpointHeadersIn{Headers: desired.Headers})
+			}
+			return nil
+		},
+	}, nil
+}
+
+// headersEqual compares an endpoint's headers with the desired ones. The values
+// of sensitive headers aren't returned by the API, so only their names are compared.
+func headersEqual(live *svix.EndpointHeadersOut, desired map[string]string) bool {
+	if len(live.Headers)+len(live.Sensitive) != len(desired) {
+		return false
+	}
+	sensitive := make(map[string]bool, len(live.Sensitive))
+	for _, name := range live.Sensitive {
+		sensitive[strings.ToLower(name)] = true
+	}
+	values := make(map[string]string, len(live.Headers))
+	for name, value := range live.Headers {
+		values[strings.ToLower(name)] = value
+	}
+	for name, value := range desired {
+		name = strings.ToLower(name)
+		if sensitive[name] {
+			continue
+		}
+		if liveValue, ok := values[name]; !ok || liveValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+// metadataOrEmpty returns empty metadata rather than nil, so updates clear
+// metadata that was removed from the state.
+func metadataOrEmpty(metadata map[string]string) *map[string]string {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	return &metadata
+}
+
+func schemasEqual(a, b map[string]map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func stringMapsEqual(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for k, v := range a {
+		if bv, ok := b[k]; !ok || bv != v {
+			return false
+		}
+	}
+	return true
+}
+
+func stringSetsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func stringPtrEqual(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func int32PtrEqual(a, b *int32) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
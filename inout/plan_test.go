@@ -0,0 +1,332 @@
+package inout
+
+import (
+	"context"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/svix/svix-cli/mock"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// newMockClient returns a client of an in-memory mock server seeded with fixtures.
+func newMockClient(t *testing.T, fixtures *mock.Fixtures) *svix.Svix {
+	t.Helper()
+	server, err := mock.NewServer("localhost", &mock.ServerOptions{Fixtures: fixtures})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	serverURL, _ := url.Parse(ts.URL)
+	return svix.New("testsk_test", &svix.SvixOptions{ServerUrl: serverURL})
+}
+
+func planStrings(t *testing.T, sc *svix.Svix, state *State, prune bool) []string {
+	t.Helper()
+	changes, err := Plan(context.Background(), sc, state, prune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := []string{}
+	for _, change := range changes {
+		out = append(out, change.String())
+	}
+	return out
+}
+
+func applyPlan(t *testing.T, sc *svix.Svix, state *State, prune bool) {
+	t.Helper()
+	changes, err := Plan(context.Background(), sc, state, prune)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, change := range changes {
+		if err := change.Apply(context.Background(), sc); err != nil {
+			t.Fatalf("%s: %s", change, ErrorDetail(err))
+		}
+	}
+}
+
+func assertPlan(t *testing.T, sc *svix.Svix, state *State, prune bool, want []string) {
+	t.Helper()
+	if got := planStrings(t, sc, state, prune); !reflect.DeepEqual(got, want) {
+		t.Errorf("plan = %q, want %q", got, want)
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func testState() *State {
+	rateLimit := int32(10)
+	return &State{
+		EventTypes: []StateEventType{
+			{Name: "invoice.paid", Description: "An invoice was paid", FeatureFlag: "beta"},
+			{Name: "user.created", Description: "A user was created", Schemas: map[string]map[string]interface{}{
+				"1": {"type": "object"},
+			}},
+		},
+		Applications: []StateApplication{{
+			Uid:       "acme",
+			Name:      "Acme Inc.",
+			RateLimit: &rateLimit,
+			Metadata:  map[string]string{"tier": "gold"},
+			Endpoints: []StateEndpoint{
+				{Uid: "billing", Url: "https://acme.example.com/billing", FilterTypes: []string{"invoice.paid"}},
+				{Url: "https://acme.example.com/all", Headers: map[string]string{"X-Tenant": "acme"}},
+			},
+		}},
+	}
+}
+
+func TestPlanCreate(t *testing.T) {
+	sc := newMockClient(t, nil)
+	state := testState()
+	assertPlan(t, sc, state, false, []string{
+		"+ event-type invoice.paid",
+		"+ event-type user.created",
+		"+ application acme",
+		"+ endpoint acme/billing",
+		"+ endpoint acme/https://acme.example.com/all",
+	})
+
+	applyPlan(t, sc, state, false)
+	assertPlan(t, sc, state, true, []string{})
+
+	et, err := sc.EventType.Get(context.Background(), "invoice.paid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flag := et.FeatureFlag.Get(); flag == nil || *flag != "beta" {
+		t.Errorf("feature flag = %v, want beta", flag)
+	}
+}
+
+func TestPlanUpdate(t *testing.T) {
+	archived := true
+	liveRateLimit := int32(5)
+	fixtures := &mock.Fixtures{
+		EventTypes: []svix.EventTypeIn{
+			{Name: "invoice.paid", Description: "Paid"},
+			{Name: "user.created", Description: "A user was created", Archived: &archived, Schemas: map[string]map[string]interface{}{
+				"1": {"type": "object"},
+			}},
+		},
+		Applications: []mock.ApplicationFixture{{
+			ApplicationIn: svix.ApplicationIn{Name: "Acme"},
+			Endpoints: []mock.EndpointFixture{
+				{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/all"}},
+				{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/old-billing", FilterTypes: []string{"invoice.paid"}}},
+			},
+		}},
+	}
+	fixtures.Applications[0].Uid.Set(strPtr("acme"))
+	fixtures.Applications[0].RateLimit.Set(&liveRateLimit)
+	fixtures.Applications[0].Endpoints[1].Uid.Set(strPtr("billing"))
+	sc := newMockClient(t, fixtures)
+
+	state := testState()
+	assertPlan(t, sc, state, false, []string{
+		"~ event-type invoice.paid (description, featureFlag)",
+		"~ event-type user.created (archived)",
+		"~ application acme (name, rateLimit, metadata)",
+		"~ endpoint acme/billing (url)",
+		"~ endpoint acme/https://acme.example.com/all (headers)",
+	})
+
+	applyPlan(t, sc, state, false)
+	assertPlan(t, sc, state, false, []string{})
+
+	// dropping the feature flag clears it
+	state.EventTypes[0].FeatureFlag = ""
+	state.EventTypes[1].Archived = true
+	assertPlan(t, sc, state, false, []string{
+		"~ event-type invoice.paid (featureFlag)",
+		"~ event-type user.created (archived)",
+	})
+	applyPlan(t, sc, state, false)
+	assertPlan(t, sc, state, false, []string{})
+}
+
+func TestPlanMatchesEndpointsByURL(t *testing.T) {
+	fixtures := &mock.Fixtures{
+		Applications: []mock.ApplicationFixture{{
+			ApplicationIn: svix.ApplicationIn{Name: "Acme"},
+			Endpoints: []mock.EndpointFixture{
+				// an endpoint with a uid isn't matched by url
+				{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/a"}},
+				{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/b", Description: strPtr("b")}},
+			},
+		}},
+	}
+	fixtures.Applications[0].Uid.Set(strPtr("acme"))
+	fixtures.Applications[0].Endpoints[0].Uid.Set(strPtr("a"))
+	sc := newMockClient(t, fixtures)
+
+	state := &State{Applications: []StateApplication{{
+		Uid:  "acme",
+		Name: "Acme",
+		Endpoints: []StateEndpoint{
+			{Url: "https://acme.example.com/a"},
+			{Url: "https://acme.example.com/b", Description: "b"},
+		},
+	}}}
+	assertPlan(t, sc, state, false, []string{
+		"+ endpoint acme/https://acme.example.com/a",
+	})
+}
+
+func TestPlanPrune(t *testing.T) {
+	fixtures := &mock.Fixtures{
+		EventTypes: []svix.EventTypeIn{
+			{Name: "invoice.paid", Description: "An invoice was paid"},
+			{Name: "legacy.event", Description: "Not in the state"},
+		},
+		Applications: []mock.ApplicationFixture{
+			{
+				ApplicationIn: svix.ApplicationIn{Name: "Acme"},
+				Endpoints: []mock.EndpointFixture{
+					{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/all"}},
+					{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/stale"}},
+				},
+			},
+			{ApplicationIn: svix.ApplicationIn{Name: "Undeclared"}},
+		},
+	}
+	flag := "beta"
+	fixtures.EventTypes[0].FeatureFlag.Set(&flag)
+	fixtures.Applications[0].Uid.Set(strPtr("acme"))
+	sc := newMockClient(t, fixtures)
+
+	state := &State{
+		EventTypes: []StateEventType{{Name: "invoice.paid", Description: "An invoice was paid", FeatureFlag: "beta"}},
+		Applications: []StateApplication{{
+			Uid:       "acme",
+			Name:      "Acme",
+			Endpoints: []StateEndpoint{{Url: "https://acme.example.com/all"}},
+		}},
+	}
+	assertPlan(t, sc, state, false, []string{})
+
+	changes := planStrings(t, sc, state, true)
+	if len(changes) != 2 || !strings.HasPrefix(changes[0], "- endpoint acme/") || !strings.HasSuffix(changes[0], "(https://acme.example.com/stale)") ||
+		changes[1] != "- event-type legacy.event" {
+		t.Errorf("plan = %q, want the stale endpoint then the legacy event type deleted", changes)
+	}
+
+	applyPlan(t, sc, state, true)
+	assertPlan(t, sc, state, true, []string{})
+	apps, err := getAllApplications(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Errorf("%d applications left, want the undeclared application kept", len(apps))
+	}
+}
+
+func TestPlanAdoptsApplicationByName(t *testing.T) {
+	fixtures := &mock.Fixtures{
+		Applications: []mock.ApplicationFixture{
+			{ApplicationIn: svix.ApplicationIn{Name: "Other"}},
+			{
+				ApplicationIn: svix.ApplicationIn{Name: "Acme"},
+				Endpoints: []mock.EndpointFixture{
+					{EndpointIn: svix.EndpointIn{Url: "https://acme.example.com/all"}},
+				},
+			},
+		},
+	}
+	sc := newMockClient(t, fixtures)
+
+	state := &State{Applications: []StateApplication{{
+		Uid:  "acme",
+		Name: "Acme",
+		Endpoints: []StateEndpoint{
+			{Url: "https://acme.example.com/all"},
+			{Url: "https://acme.example.com/new"},
+		},
+	}}}
+	assertPlan(t, sc, state, false, []string{
+		"~ application acme (uid)",
+		"+ endpoint acme/https://acme.example.com/new",
+	})
+
+	applyPlan(t, sc, state, false)
+	assertPlan(t, sc, state, false, []string{})
+	apps, err := getAllApplications(context.Background(), sc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apps) != 2 {
+		t.Errorf("%d applications, want the existing application adopted rather than a new one", len(apps))
+	}
+}
+
+func TestHeadersEqual(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   map[string]string
+		sensitive []string
+		desired   map[string]string
+		want      bool
+	}{
+		{name: "none", headers: map[string]string{}, desired: map[string]string{}, want: true},
+		{name: "equal", headers: map[string]string{"X-Tenant": "acme"}, desired: map[string]string{"X-Tenant": "acme"}, want: true},
+		{name: "names are case insensitive", headers: map[string]string{"x-tenant": "acme"}, desired: map[string]string{"X-Tenant": "acme"}, want: true},
+		{name: "values are case sensitive", headers: map[string]string{"X-Tenant": "acme"}, desired: map[string]string{"X-Tenant": "ACME"}, want: false},
+		{name: "different value", headers: map[string]string{"X-Tenant": "acme"}, desired: map[string]string{"X-Tenant": "other"}, want: false},
+		{name: "missing", headers: map[string]string{}, desired: map[string]string{"X-Tenant": "acme"}, want: false},
+		{name: "extra", headers: map[string]string{"X-Tenant": "acme", "X-Extra": "1"}, desired: map[string]string{"X-Tenant": "acme"}, want: false},
+		{name: "sensitive values aren't compared", headers: map[string]string{}, sensitive: []string{"Authorization"}, desired: map[string]string{"authorization": "Bearer x"}, want: true},
+		{name: "sensitive header missing", headers: map[string]string{}, sensitive: []string{"Authorization"}, desired: map[string]string{}, want: false},
+		{name: "other header instead of a sensitive one", headers: map[string]string{}, sensitive: []string{"Authorization"}, desired: map[string]string{"X-Api-Key": "x"}, want: false},
+	}
+	for _, tt := range tests {
+		live := &svix.EndpointHeadersOut{Headers: tt.headers, Sensitive: tt.sensitive}
+		if got := headersEqual(live, tt.desired); got != tt.want {
+			t.Errorf("%s: headersEqual = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadState(t *testing.T) {
+	tests := []struct {
+		name    string
+		state   string
+		wantErr string
+	}{
+		{name: "valid", state: "eventTypes:\n  - name: invoice.paid\n    featureFlag: beta\n    archived: true\napplications:\n  - uid: acme\n    name: Acme\n    endpoints:\n      - url: https://acme.example.com/\n"},
+		{name: "application without uid", state: "applications:\n  - name: Acme\n", wantErr: "has no uid"},
+		{name: "application without name", state: "applications:\n  - uid: acme\n", wantErr: "has no name"},
+		{name: "duplicate application", state: "applications:\n  - {uid: acme, name: a}\n  - {uid: acme, name: b}\n", wantErr: "declared twice"},
+		{name: "duplicate endpoint url", state: "applications:\n  - uid: acme\n    name: a\n    endpoints: [{url: https://a/}, {url: https://a/}]\n", wantErr: "declared twice"},
+		{name: "event type without name", state: "eventTypes:\n  - description: x\n", wantErr: "has no name"},
+		{name: "unknown field", state: "eventTypes:\n  - name: a\n    color: red\n", wantErr: "unknown field"},
+	}
+	for _, tt := range tests {
+		fileName := filepath.Join(t.TempDir(), "svix.yaml")
+		if err := os.WriteFile(fileName, []byte(tt.state), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		state, err := LoadState(fileName)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: %s", tt.name, err)
+			} else if !state.EventTypes[0].Archived || state.EventTypes[0].FeatureFlag != "beta" {
+				t.Errorf("%s: event type = %+v", tt.name, state.EventTypes[0])
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: err = %v, want it to contain %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
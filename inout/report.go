@@ -0,0 +1,67 @@
+package inout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+const (
+	OutcomeCreated   = "created"
+	OutcomeUpdated   = "updated"
+	OutcomeSkipped   = "skipped"
+	OutcomeUnchanged = "unchanged"
+	OutcomeFailed    = "failed"
+)
+
+// ImportReport is the outcome of every item of an import. In a dry run, the
+// outcomes are what would have happened.
+type ImportReport struct {
+	DryRun  bool           `json:"dryRun"`
+	Results []ImportResult `json:"results"`
+	Counts  map[string]int `json:"counts"`
+}
+
+type ImportResult struct {
+	Name    string `json:"name"`
+	Outcome string `json:"outcome"`
+	Error   string `json:"error,omitempty"`
+}
+
+func newImportReport(dryRun bool) *ImportReport {
+	return &ImportReport{
+		DryRun:  dryRun,
+		Results: []ImportResult{},
+		Counts:  map[string]int{},
+	}
+}
+
+func (r *ImportReport) add(name string, outcome string, err error) {
+	result := ImportResult{Name: name, Outcome: outcome}
+	if err != nil {
+		result.Outcome = OutcomeFailed
+		result.Error = ErrorDetail(err)
+	}
+	r.Results = append(r.Results, result)
+	r.Counts[result.Outcome]++
+}
+
+// Failed reports whether any item failed to import.
+func (r *ImportReport) Failed() bool {
+	return r.Counts[OutcomeFailed] > 0
+}
+
+// ErrorDetail includes the body of API errors, which says why the request failed.
+func ErrorDetail(err error) string {
+	sErr, ok := err.(*svix.Error)
+	if !ok || len(sErr.Body()) == 0 {
+		return err.Error()
+	}
+	var body bytes.Buffer
+	if json.Compact(&body, sErr.Body()) != nil {
+		return err.Error()
+	}
+	return fmt.Sprintf("%s: %s", err, body.String())
+}
@@ -0,0 +1,173 @@
+package inout
+
+import (
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+const (
+	DefaultMaxRetries = 5
+
+	minBackoff = 500 * time.Millisecond
+	maxBackoff = 30 * time.Second
+	// attemptTimeout matches the timeout of the svix client's default http client
+	attemptTimeout = 60 * time.Second
+)
+
+// RetryTransport retries requests that were rate limited (429), failed with a
+// server error (5xx) or didn't get a response. It waits as long as the
+// Retry-After header asks, or backs off exponentially otherwise. Only requests
+// that are safe to send twice are retried: those with an idempotent method, and
+// those with an Idempotency-Key header (see idempotentPostOptions).
+type RetryTransport struct {
+	Base       http.RoundTripper
+	MaxRetries int
+	// OnRetry is called before waiting to retry a request
+	OnRetry func(wait time.Duration)
+}
+
+// NewRetryingHTTPClient returns an http client for the svix client that retries
+// failed requests up to maxRetries times.
+func NewRetryingHTTPClient(maxRetries int, onRetry func(wait time.Duration)) *http.Client {
+	return &http.Client{
+		Transport: &RetryTransport{
+			Base:       http.DefaultTransport,
+			MaxRetries: maxRetries,
+			OnRetry:    onRetry,
+		},
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	canRetry := isRetryable(req)
+	for attempt := 0; ; attempt++ {
+		attemptReq := req
+		if attempt > 0 && req.Body != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			attemptReq = req.Clone(req.Context())
+			attemptReq.Body = body
+		}
+
+		res, err := t.roundTrip(attemptReq)
+		if !canRetry || attempt >= t.MaxRetries || !shouldRetry(res, err) {
+			return res, err
+		}
+
+		wait := backoff(attempt)
+		if res != nil {
+			if retryAfter, ok := parseRetryAfter(res.Header.Get("Retry-After")); ok {
+				wait = retryAfter
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+		}
+		if t.OnRetry != nil {
+			t.OnRetry(wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+// roundTrip makes a single attempt, timing out if it takes too long.
+func (t *RetryTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), attemptTimeout)
+	res, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+// isRetryable reports whether a request can be sent again. The svix client
+// retries server errors itself, and the two layers add up rather than multiply:
+// the first attempt is retried here up to MaxRetries times, then the svix client
+// sends up to 2 more, each marked with a svix-retry-count header and sent once.
+func isRetryable(req *http.Request) bool {
+	if req.Body != nil && req.GetBody == nil {
+		return false
+	}
+	if req.Header.Get("svix-retry-count") != "" {
+		return false
+	}
+	if req.Header.Get("idempotency-key") != "" {
+		return true
+	}
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// idempotentPostOptions returns the options of a create request with a new
+// idempotency key, which makes the request safe to retry.
+func idempotentPostOptions() *svix.PostOptions {
+	idempotencyKey := "svix-cli-" + utils.RandomString(24)
+	return &svix.PostOptions{IdempotencyKey: &idempotencyKey}
+}
+
+func shouldRetry(res *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500
+}
+
+// backoff is the exponential backoff before a retry, with jitter so parallel
+// requests don't all retry at once.
+func backoff(attempt int) time.Duration {
+	wait := minBackoff << uint(attempt)
+	if wait > maxBackoff || wait <= 0 {
+		wait = maxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or as an http date.
+func parseRetryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(header); err == nil {
+		wait := time.Until(date)
+		if wait < 0 {
+			wait = 0
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// cancelOnClose cancels an attempt's context once its response body is closed,
+// so the timeout covers reading the body too.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
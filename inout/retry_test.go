@@ -0,0 +1,196 @@
+package inout
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// retryServer responds with the statuses in turn, then with 200, and records
+// the body of every request it gets.
+type retryServer struct {
+	mu         sync.Mutex
+	statuses   []int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *retryServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bodies = append(s.bodies, string(body))
+	if len(s.statuses) == 0 {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	w.WriteHeader(s.statuses[0])
+	s.statuses = s.statuses[1:]
+}
+
+func (s *retryServer) requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.bodies)
+}
+
+func TestRetryTransport(t *testing.T) {
+	pastDate := time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)
+	tests := []struct {
+		name         string
+		method       string
+		header       map[string]string
+		statuses     []int
+		retryAfter   string
+		wantStatus   int
+		wantRequests int
+	}{
+		{name: "rate limited", method: http.MethodGet, statuses: []int{429, 429}, retryAfter: "0", wantStatus: 200, wantRequests: 3},
+		{name: "server error", method: http.MethodDelete, statuses: []int{503}, retryAfter: "0", wantStatus: 200, wantRequests: 2},
+		{name: "retry after a date", method: http.MethodGet, statuses: []int{503}, retryAfter: pastDate, wantStatus: 200, wantRequests: 2},
+		{name: "gives up after max retries", method: http.MethodGet, statuses: []int{503, 503, 503, 503}, retryAfter: "0", wantStatus: 503, wantRequests: 3},
+		{name: "client error", method: http.MethodGet, statuses: []int{404}, wantStatus: 404, wantRequests: 1},
+		{name: "post", method: http.MethodPost, statuses: []int{503}, retryAfter: "0", wantStatus: 503, wantRequests: 1},
+		{name: "post with idempotency key", method: http.MethodPost, header: map[string]string{"idempotency-key": "key"}, statuses: []int{429, 503}, retryAfter: "0", wantStatus: 200, wantRequests: 3},
+		{name: "retried by the svix client", method: http.MethodGet, header: map[string]string{"svix-retry-count": "1"}, statuses: []int{503}, retryAfter: "0", wantStatus: 503, wantRequests: 1},
+	}
+	for _, tt := range tests {
+		server := &retryServer{statuses: tt.statuses, retryAfter: tt.retryAfter}
+		ts := httptest.NewServer(server)
+
+		var waits []time.Duration
+		client := &http.Client{Transport: &RetryTransport{
+			Base:       http.DefaultTransport,
+			MaxRetries: 2,
+			OnRetry:    func(wait time.Duration) { waits = append(waits, wait) },
+		}}
+		req, err := http.NewRequest(tt.method, ts.URL, strings.NewReader(`{"a":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range tt.header {
+			req.Header.Set(name, value)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			ts.Close()
+			continue
+		}
+		res.Body.Close()
+		ts.Close()
+
+		if res.StatusCode != tt.wantStatus {
+			t.Errorf("%s: status = %d, want %d", tt.name, res.StatusCode, tt.wantStatus)
+		}
+		if server.requests() != tt.wantRequests {
+			t.Errorf("%s: sent %d requests, want %d", tt.name, server.requests(), tt.wantRequests)
+		}
+		if len(waits) != tt.wantRequests-1 {
+			t.Errorf("%s: waited %d times, want %d", tt.name, len(waits), tt.wantRequests-1)
+		}
+		for _, wait := range waits {
+			if wait != 0 {
+				t.Errorf("%s: waited %s, want the 0s Retry-After asked for", tt.name, wait)
+			}
+		}
+		// every retry sends the whole body again
+		for i, body := range server.bodies {
+			if body != `{"a":1}` {
+				t.Errorf("%s: request %d body = %q", tt.name, i, body)
+			}
+		}
+	}
+}
+
+func TestRetryTransportBacksOffWithoutRetryAfter(t *testing.T) {
+	server := &retryServer{statuses: []int{503}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	var waits []time.Duration
+	client := &http.Client{Transport: &RetryTransport{
+		Base:       http.DefaultTransport,
+		MaxRetries: 1,
+		OnRetry:    func(wait time.Duration) { waits = append(waits, wait) },
+	}}
+	res, err := client.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", res.StatusCode)
+	}
+	if len(waits) != 1 || waits[0] < minBackoff/2 || waits[0] > minBackoff {
+		t.Errorf("waited %v, want a single backoff between %s and %s", waits, minBackoff/2, minBackoff)
+	}
+}
+
+func TestRetryTransportBodyWithoutGetBody(t *testing.T) {
+	server := &retryServer{statuses: []int{503}, retryAfter: "0"}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	client := &http.Client{Transport: &RetryTransport{Base: http.DefaultTransport, MaxRetries: 2}}
+	// a body that can't be replayed is only sent once
+	req, err := http.NewRequest(http.MethodPut, ts.URL, io.NopCloser(strings.NewReader("body")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable || server.requests() != 1 {
+		t.Errorf("status = %d after %d requests, want 503 after 1", res.StatusCode, server.requests())
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+		wantOk bool
+	}{
+		{header: "", wantOk: false},
+		{header: "0", want: 0, wantOk: true},
+		{header: "120", want: 2 * time.Minute, wantOk: true},
+		{header: "-1", wantOk: false},
+		{header: "soon", wantOk: false},
+		{header: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), want: 0, wantOk: true},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.header)
+		if ok != tt.wantOk || got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, %v, want %s, %v", tt.header, got, ok, tt.want, tt.wantOk)
+		}
+	}
+
+	// a date is relative to now, and only has a precision of a second
+	got, ok := parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat))
+	if !ok || got < 58*time.Second || got > time.Minute {
+		t.Errorf("parseRetryAfter(a minute from now) = %s, %v", got, ok)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 80; attempt++ {
+		wait := backoff(attempt)
+		want := maxBackoff
+		if attempt < 6 {
+			want = minBackoff << uint(attempt)
+		}
+		if wait < want/2 || wait > want {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, wait, want/2, want)
+		}
+	}
+}
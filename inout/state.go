@@ -0,0 +1,118 @@
+package inout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// State is the desired state of an organization, as declared in a svix.yaml
+// file and reconciled by svix apply.
+type State struct {
+	EventTypes   []StateEventType   `json:"eventTypes"`
+	Applications []StateApplication `json:"applications"`
+}
+
+// StateEventType is an event type, identified by its name. It has no feature
+// flag and isn't archived unless they are set.
+type StateEventType struct {
+	Name        string                            `json:"name"`
+	Description string                            `json:"description"`
+	FeatureFlag string                            `json:"featureFlag,omitempty"`
+	Archived    bool                              `json:"archived,omitempty"`
+	Schemas     map[string]map[string]interface{} `json:"schemas,omitempty"`
+}
+
+// StateApplication is an application, identified by its uid. An existing
+// application without a uid and with the same name is adopted, getting the uid.
+type StateApplication struct {
+	Uid       string            `json:"uid"`
+	Name      string            `json:"name"`
+	RateLimit *int32            `json:"rateLimit,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Endpoints []StateEndpoint   `json:"endpoints"`
+}
+
+// StateEndpoint is an endpoint, identified by its uid if it has one or by its
+// url otherwise. Headers are only managed if they are set.
+type StateEndpoint struct {
+	Uid         string            `json:"uid,omitempty"`
+	Url         string            `json:"url"`
+	Description string            `json:"description,omitempty"`
+	FilterTypes []string          `json:"filterTypes,omitempty"`
+	Channels    []string          `json:"channels,omitempty"`
+	RateLimit   *int32            `json:"rateLimit,omitempty"`
+	Disabled    bool              `json:"disabled,omitempty"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+}
+
+// key identifies the endpoint within its application.
+func (ep *StateEndpoint) key() string {
+	if ep.Uid != "" {
+		return ep.Uid
+	}
+	return ep.Url
+}
+
+// LoadState reads a desired state file, in yaml or json.
+func LoadState(fileName string) (*State, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	data, err = yamlToJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", fileName, err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var state State
+	if err := dec.Decode(&state); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", fileName, err)
+	}
+	if err := state.validate(); err != nil {
+		return nil, fmt.Errorf("invalid state file %s: %s", fileName, err)
+	}
+	return &state, nil
+}
+
+func (s *State) validate() error {
+	eventTypes := map[string]bool{}
+	for i, et := range s.EventTypes {
+		if et.Name == "" {
+			return fmt.Errorf("event type %d has no name", i)
+		}
+		if eventTypes[et.Name] {
+			return fmt.Errorf("event type %s is declared twice", et.Name)
+		}
+		eventTypes[et.Name] = true
+	}
+
+	apps := map[string]bool{}
+	for i, app := range s.Applications {
+		if app.Uid == "" {
+			return fmt.Errorf("application %d has no uid, applications are identified by their uid", i)
+		}
+		if app.Name == "" {
+			return fmt.Errorf("application %s has no name", app.Uid)
+		}
+		if apps[app.Uid] {
+			return fmt.Errorf("application %s is declared twice", app.Uid)
+		}
+		apps[app.Uid] = true
+
+		endpoints := map[string]bool{}
+		for j, ep := range app.Endpoints {
+			if ep.Url == "" {
+				return fmt.Errorf("endpoint %d of application %s has no url", j, app.Uid)
+			}
+			if endpoints[ep.key()] {
+				return fmt.Errorf("endpoint %s of application %s is declared twice", ep.key(), app.Uid)
+			}
+			endpoints[ep.key()] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package inout
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yamlToJSON converts a yaml document to json, so it can be decoded into
+// the json tagged svix types. Any json document is valid yaml.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonCompatible(v))
+}
+
+// jsonToYAML converts a json tagged value to yaml, keeping its json field names.
+func jsonToYAML(v interface{}) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var generic interface{}
+	if err := dec.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(yamlCompatible(generic))
+}
+
+// yamlCompatible replaces json numbers with ints or floats, so integers aren't
+// written in exponent notation or as strings.
+func yamlCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = yamlCompatible(value)
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = yamlCompatible(v[i])
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return v
+	}
+}
+
+// jsonCompatible replaces the map[interface{}]interface{} maps yaml decodes
+// into with map[string]interface{}, which encoding/json can marshal.
+func jsonCompatible(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprint(key)] = jsonCompatible(value)
+		}
+		return m
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonCompatible(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,145 @@
+package mock
+
+import (
+	"net/http"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func (s *Server) listApplications(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	apps := s.store.apps
+	ids := make([]string, len(apps))
+	for i, app := range apps {
+		ids[i] = app.out.Id
+	}
+	indexes, iterator, done := page(r, ids, false)
+
+	out := svix.ListResponseApplicationOut{
+		Data: []svix.ApplicationOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, apps[i].out)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.ApplicationIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if uid := in.Uid.Get(); uid != nil {
+		if existing := s.store.app(*uid); existing != nil {
+			if r.URL.Query().Get("get_if_exists") == "true" {
+				writeJSON(w, http.StatusOK, existing.out)
+				return
+			}
+			writeError(w, http.StatusConflict, "conflict", "An application with this uid already exists")
+			return
+		}
+	}
+	app, err := s.store.createApp("", &in)
+	if err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	writeJSON(w, http.StatusCreated, app.out)
+}
+
+func (s *Server) getApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, app.out)
+}
+
+func (s *Server) updateApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.ApplicationIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	if in.Name == "" {
+		writeValidationError(w, "name", "field required")
+		return
+	}
+	if uid := in.Uid.Get(); uid != nil {
+		if existing := s.store.app(*uid); existing != nil && existing != app {
+			writeError(w, http.StatusConflict, "conflict", "An application with this uid already exists")
+			return
+		}
+	}
+	app.out.Name = in.Name
+	app.out.Uid = in.Uid
+	app.out.RateLimit = in.RateLimit
+	app.out.Metadata = map[string]string{}
+	if in.Metadata != nil {
+		app.out.Metadata = *in.Metadata
+	}
+	app.out.UpdatedAt = now()
+	writeJSON(w, http.StatusOK, app.out)
+}
+
+func (s *Server) deleteApplication(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	s.store.removeApp(app)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// createApp adds an application, using id if set.
+func (s *store) createApp(id string, in *svix.ApplicationIn) (*application, *validationError) {
+	if in.Name == "" {
+		return nil, &validationError{"name", "field required"}
+	}
+	if id == "" {
+		id = utils.NewID("app")
+	}
+	metadata := map[string]string{}
+	if in.Metadata != nil {
+		metadata = *in.Metadata
+	}
+	createdAt := now()
+	app := &application{
+		out: svix.ApplicationOut{
+			Id:        id,
+			Name:      in.Name,
+			Uid:       in.Uid,
+			RateLimit: in.RateLimit,
+			Metadata:  metadata,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		},
+	}
+	s.apps = append(s.apps, app)
+	return app, nil
+}
@@ -0,0 +1,31 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// appPortalAccess hands out a token for an application's portal, the url points
+// at the mock server since there is no portal to log in to.
+func (s *Server) appPortalAccess(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	token := "appsk_" + utils.RandomString(32)
+	writeJSON(w, http.StatusOK, svix.AppPortalAccessOut{
+		Token: token,
+		Url:   fmt.Sprintf("http://%s/app-portal/login/%s#key=%s", s.addr, app.out.Id, token),
+	})
+}
+
+func (s *Server) logout(w http.ResponseWriter, r *http.Request, params []string) {
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,147 @@
+package mock
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/svix/svix-cli/sender"
+	"github.com/svix/svix-cli/signature"
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+const maxAttemptResponse = 4 << 10 // 4KiB
+
+// deliver sends a new message to every endpoint that accepts it, callers must hold s.store.mu.
+func (s *Server) deliver(app *application, msg *message) {
+	for _, ep := range app.endpoints {
+		if ep.accepts(&msg.out) {
+			go s.deliverTo(app.out.Id, msg.out.Id, ep.out.Id)
+		}
+	}
+}
+
+// deliverTo attempts to send a message to an endpoint until it succeeds or
+// the retry schedule is exhausted.
+func (s *Server) deliverTo(appID string, msgID string, endpointID string) {
+	for retry := 0; ; retry++ {
+		succeeded, ok := s.attempt(appID, msgID, endpointID, false)
+		if succeeded || !ok || retry >= len(s.retrySchedule) {
+			s.setNextAttempt(appID, msgID, endpointID, nil)
+			return
+		}
+
+		next := now().Add(s.retrySchedule[retry])
+		s.setNextAttempt(appID, msgID, endpointID, &next)
+		select {
+		case <-time.After(s.retrySchedule[retry]):
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt makes a single delivery attempt and records it. ok is false if the
+// message can no longer be delivered to the endpoint, e.g. it was deleted or disabled.
+func (s *Server) attempt(appID string, msgID string, endpointID string, manual bool) (succeeded bool, ok bool) {
+	s.store.mu.Lock()
+	app, msg := s.store.appMessage(appID, msgID)
+	var ep *endpoint
+	if msg != nil {
+		ep = app.endpoint(endpointID)
+	}
+	if ep == nil || ep.disabled() {
+		s.store.mu.Unlock()
+		return false, false
+	}
+	url := ep.out.Url
+	payload, err := json.Marshal(msg.out.Payload)
+	var req *http.Request
+	if err == nil {
+		var key *signature.Key
+		if key, err = signature.ParseKey(ep.secret); err == nil {
+			req, err = sender.NewRequest(s.ctx, url, key, signature.SvixHeaderPrefix, msg.out.Id, payload, ep.headers)
+		}
+	}
+	s.store.mu.Unlock()
+	if err != nil {
+		color.Red("Failed to build request for %s: %s\n", msgID, err)
+		return false, false
+	}
+
+	color.Blue("-> Sending %s (%s) to %s\n", msgID, msg.out.EventType, url)
+	attempt := &svix.MessageAttemptOut{
+		Id:         utils.NewID("atmpt"),
+		EndpointId: endpointID,
+		MsgId:      msgID,
+		Url:        url,
+		Status:     2,
+	}
+	if manual {
+		attempt.TriggerType = 1
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		color.Red("<- %s failed: %s\n", url, err)
+		attempt.Response = err.Error()
+	} else {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxAttemptResponse))
+		res.Body.Close()
+		attempt.Response = string(body)
+		attempt.ResponseStatusCode = int32(res.StatusCode)
+		if res.StatusCode >= 200 && res.StatusCode < 300 {
+			attempt.Status = 0
+			color.Green("<- %s responded with \"%s\"\n", url, res.Status)
+		} else {
+			color.Red("<- %s responded with \"%s\"\n", url, res.Status)
+		}
+	}
+	attempt.Timestamp = now()
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	msg.attempts = append(msg.attempts, attempt)
+	return attempt.Status == 0, true
+}
+
+func (s *Server) setNextAttempt(appID string, msgID string, endpointID string, next *time.Time) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, msg := s.store.appMessage(appID, msgID)
+	if msg == nil {
+		return
+	}
+	if next == nil {
+		delete(msg.nextAttempts, endpointID)
+	} else {
+		msg.nextAttempts[endpointID] = *next
+	}
+}
+
+// accepts reports whether a message should be sent to the endpoint, based on
+// its event type filter and channels.
+func (ep *endpoint) accepts(msg *svix.MessageOut) bool {
+	if ep.disabled() {
+		return false
+	}
+	if len(ep.out.FilterTypes) > 0 && !containsString(ep.out.FilterTypes, msg.EventType) {
+		return false
+	}
+	if len(ep.out.Channels) > 0 {
+		for _, channel := range msg.Channels {
+			if containsString(ep.out.Channels, channel) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (ep *endpoint) disabled() bool {
+	return ep.out.Disabled != nil && *ep.out.Disabled
+}
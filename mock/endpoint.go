@@ -0,0 +1,314 @@
+package mock
+
+import (
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func (s *Server) listEndpoints(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	ids := make([]string, len(app.endpoints))
+	for i, ep := range app.endpoints {
+		ids[i] = ep.out.Id
+	}
+	indexes, iterator, done := page(r, ids, false)
+
+	out := svix.ListResponseEndpointOut{
+		Data: []svix.EndpointOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, app.endpoints[i].out)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EndpointIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	if uid := in.Uid.Get(); uid != nil && app.endpoint(*uid) != nil {
+		writeError(w, http.StatusConflict, "conflict", "An endpoint with this uid already exists")
+		return
+	}
+	ep, err := app.createEndpoint("", &in)
+	if err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ep.out)
+}
+
+func (s *Server) getEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, ep.out)
+}
+
+func (s *Server) updateEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EndpointUpdate
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	if err := validateEndpointURL(in.Url); err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	if uid := in.Uid.Get(); uid != nil {
+		if existing := app.endpoint(*uid); existing != nil && existing != ep {
+			writeError(w, http.StatusConflict, "conflict", "An endpoint with this uid already exists")
+			return
+		}
+	}
+
+	ep.out.Url = in.Url
+	ep.out.Uid = in.Uid
+	ep.out.RateLimit = in.RateLimit
+	ep.out.Channels = in.Channels
+	ep.out.FilterTypes = in.FilterTypes
+	ep.out.Disabled = in.Disabled
+	ep.out.Description = ""
+	if in.Description != nil {
+		ep.out.Description = *in.Description
+	}
+	ep.out.Metadata = map[string]string{}
+	if in.Metadata != nil {
+		ep.out.Metadata = *in.Metadata
+	}
+	if version := in.Version.Get(); version != nil {
+		ep.out.Version = *version
+	}
+	ep.out.UpdatedAt = now()
+	writeJSON(w, http.StatusOK, ep.out)
+}
+
+func (s *Server) deleteEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	app.removeEndpoint(ep)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getEndpointSecret(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, svix.EndpointSecretOut{Key: ep.secret})
+}
+
+func (s *Server) rotateEndpointSecret(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EndpointSecretRotateIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	secret := newEndpointSecret()
+	if key := in.Key.Get(); key != nil {
+		var err *validationError
+		if secret, err = normalizeEndpointSecret(*key); err != nil {
+			writeValidationError(w, "key", err.msg)
+			return
+		}
+	}
+	ep.secret = secret
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getEndpointHeaders(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, svix.EndpointHeadersOut{
+		Headers:   ep.headers,
+		Sensitive: []string{},
+	})
+}
+
+func (s *Server) updateEndpointHeaders(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EndpointHeadersIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	ep.headers = map[string]string{}
+	for name, value := range in.Headers {
+		ep.headers[name] = value
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) patchEndpointHeaders(w http.ResponseWriter, r *http.Request, params []string) {
+	// headers set to null are removed
+	var in struct {
+		Headers map[string]*string `json:"headers"`
+	}
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	for name, value := range in.Headers {
+		if value == nil {
+			delete(ep.headers, name)
+		} else {
+			ep.headers[name] = *value
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *store) appEndpoint(appID string, endpointID string) (*application, *endpoint) {
+	app := s.app(appID)
+	if app == nil {
+		return nil, nil
+	}
+	return app, app.endpoint(endpointID)
+}
+
+// createEndpoint adds an endpoint, using id if set.
+func (a *application) createEndpoint(id string, in *svix.EndpointIn) (*endpoint, *validationError) {
+	if err := validateEndpointURL(in.Url); err != nil {
+		return nil, err
+	}
+	secret := newEndpointSecret()
+	if s := in.Secret.Get(); s != nil {
+		var err *validationError
+		if secret, err = normalizeEndpointSecret(*s); err != nil {
+			return nil, err
+		}
+	}
+	if id == "" {
+		id = utils.NewID("ep")
+	}
+	description := ""
+	if in.Description != nil {
+		description = *in.Description
+	}
+	metadata := map[string]string{}
+	if in.Metadata != nil {
+		metadata = *in.Metadata
+	}
+	version := int32(1)
+	if v := in.Version.Get(); v != nil {
+		version = *v
+	}
+
+	createdAt := now()
+	ep := &endpoint{
+		out: svix.EndpointOut{
+			Id:          id,
+			Url:         in.Url,
+			Uid:         in.Uid,
+			Description: description,
+			RateLimit:   in.RateLimit,
+			Channels:    in.Channels,
+			FilterTypes: in.FilterTypes,
+			Disabled:    in.Disabled,
+			Metadata:    metadata,
+			Version:     version,
+			CreatedAt:   createdAt,
+			UpdatedAt:   createdAt,
+		},
+		secret:  secret,
+		headers: map[string]string{},
+	}
+	a.endpoints = append(a.endpoints, ep)
+	return ep, nil
+}
+
+func validateEndpointURL(raw string) *validationError {
+	if raw == "" {
+		return &validationError{"url", "field required"}
+	}
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return &validationError{"url", "invalid or missing URL scheme"}
+	}
+	return nil
+}
+
+// normalizeEndpointSecret checks the secret is base64 encoded, and adds the whsec_ prefix.
+func normalizeEndpointSecret(secret string) (string, *validationError) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+	if err != nil || len(key) < 24 || len(key) > 75 {
+		return "", &validationError{"secret", "secrets must be 24 to 75 base64 encoded bytes, optionally prefixed with whsec_"}
+	}
+	return "whsec_" + strings.TrimPrefix(secret, "whsec_"), nil
+}
@@ -0,0 +1,146 @@
+package mock
+
+import (
+	"net/http"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func (s *Server) listEventTypes(w http.ResponseWriter, r *http.Request, params []string) {
+	includeArchived := r.URL.Query().Get("include_archived") == "true"
+	withContent := r.URL.Query().Get("with_content") == "true"
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	var eventTypes []*svix.EventTypeOut
+	for _, et := range s.store.sortedEventTypes() {
+		if includeArchived || et.Archived == nil || !*et.Archived {
+			eventTypes = append(eventTypes, et)
+		}
+	}
+	ids := make([]string, len(eventTypes))
+	for i, et := range eventTypes {
+		ids[i] = et.Name
+	}
+	indexes, iterator, done := page(r, ids, false)
+
+	out := svix.ListResponseEventTypeOut{
+		Data: []svix.EventTypeOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		et := *eventTypes[i]
+		if !withContent {
+			et.Schemas = nil
+		}
+		out.Data = append(out.Data, et)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createEventType(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EventTypeIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	if _, ok := s.store.eventTypes[in.Name]; ok {
+		writeError(w, http.StatusConflict, "event_type_exists", "An event type with this name already exists")
+		return
+	}
+	et, err := s.store.createEventType(&in)
+	if err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	writeJSON(w, http.StatusCreated, et)
+}
+
+func (s *Server) getEventType(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	et, ok := s.store.eventTypes[params[0]]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, et)
+}
+
+// updateEventType replaces an event type, creating it if it doesn't exist.
+func (s *Server) updateEventType(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.EventTypeUpdate
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	et, ok := s.store.eventTypes[params[0]]
+	if !ok {
+		created, err := s.store.createEventType(&svix.EventTypeIn{
+			Name:        params[0],
+			Description: in.Description,
+			Archived:    in.Archived,
+			FeatureFlag: in.FeatureFlag,
+			Schemas:     in.Schemas,
+		})
+		if err != nil {
+			writeValidationError(w, err.field, err.msg)
+			return
+		}
+		writeJSON(w, http.StatusCreated, created)
+		return
+	}
+	et.Description = in.Description
+	et.Archived = in.Archived
+	et.FeatureFlag = in.FeatureFlag
+	et.Schemas = in.Schemas
+	et.UpdatedAt = now()
+	writeJSON(w, http.StatusOK, et)
+}
+
+// deleteEventType archives an event type, or deletes it when expunge is set.
+func (s *Server) deleteEventType(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	et, ok := s.store.eventTypes[params[0]]
+	if !ok {
+		writeNotFound(w)
+		return
+	}
+	if r.URL.Query().Get("expunge") == "true" {
+		delete(s.store.eventTypes, et.Name)
+	} else {
+		archived := true
+		et.Archived = &archived
+		et.UpdatedAt = now()
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *store) createEventType(in *svix.EventTypeIn) (*svix.EventTypeOut, *validationError) {
+	if in.Name == "" {
+		return nil, &validationError{"name", "field required"}
+	}
+	createdAt := now()
+	et := &svix.EventTypeOut{
+		Name:        in.Name,
+		Description: in.Description,
+		Archived:    in.Archived,
+		FeatureFlag: in.FeatureFlag,
+		Schemas:     in.Schemas,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+	s.eventTypes[in.Name] = et
+	return et, nil
+}
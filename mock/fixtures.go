@@ -0,0 +1,88 @@
+package mock
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// Fixtures describe the state a mock server starts with. IDs are optional, and
+// are generated when missing.
+type Fixtures struct {
+	EventTypes   []svix.EventTypeIn   `json:"eventTypes"`
+	Applications []ApplicationFixture `json:"applications"`
+}
+
+type ApplicationFixture struct {
+	ID string `json:"id"`
+	svix.ApplicationIn
+	Endpoints    []EndpointFixture    `json:"endpoints"`
+	Integrations []IntegrationFixture `json:"integrations"`
+	Messages     []MessageFixture     `json:"messages"`
+}
+
+type EndpointFixture struct {
+	ID string `json:"id"`
+	svix.EndpointIn
+}
+
+type IntegrationFixture struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type MessageFixture struct {
+	ID string `json:"id"`
+	svix.MessageIn
+}
+
+func LoadFixtures(fileName string) (*Fixtures, error) {
+	data, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixtures
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("invalid fixtures file %s: %s", fileName, err)
+	}
+	return &f, nil
+}
+
+func (s *store) seed(f *Fixtures) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range f.EventTypes {
+		if _, err := s.createEventType(&f.EventTypes[i]); err != nil {
+			return fmt.Errorf("event type %d: %s %s", i, err.field, err.msg)
+		}
+	}
+	for i := range f.Applications {
+		appFixture := &f.Applications[i]
+		app, err := s.createApp(appFixture.ID, &appFixture.ApplicationIn)
+		if err != nil {
+			return fmt.Errorf("application %d: %s %s", i, err.field, err.msg)
+		}
+		for j := range appFixture.Endpoints {
+			epFixture := &appFixture.Endpoints[j]
+			if _, err := app.createEndpoint(epFixture.ID, &epFixture.EndpointIn); err != nil {
+				return fmt.Errorf("application %s endpoint %d: %s %s", app.out.Id, j, err.field, err.msg)
+			}
+		}
+		for j, integFixture := range appFixture.Integrations {
+			if _, err := app.createIntegration(integFixture.ID, integFixture.Name, integFixture.Key); err != nil {
+				return fmt.Errorf("application %s integration %d: %s %s", app.out.Id, j, err.field, err.msg)
+			}
+		}
+		for j := range appFixture.Messages {
+			msgFixture := &appFixture.Messages[j]
+			if _, err := app.createMessage(msgFixture.ID, &msgFixture.MessageIn); err != nil {
+				return fmt.Errorf("application %s message %d: %s %s", app.out.Id, j, err.field, err.msg)
+			}
+		}
+	}
+	return nil
+}
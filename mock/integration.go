@@ -0,0 +1,166 @@
+package mock
+
+import (
+	"net/http"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func (s *Server) listIntegrations(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	ids := make([]string, len(app.integrations))
+	for i, integ := range app.integrations {
+		ids[i] = integ.out.Id
+	}
+	indexes, iterator, done := page(r, ids, false)
+
+	out := svix.ListResponseIntegrationOut{
+		Data: []svix.IntegrationOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, app.integrations[i].out)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createIntegration(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.IntegrationIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	integ, err := app.createIntegration("", in.Name, "")
+	if err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	writeJSON(w, http.StatusCreated, integ.out)
+}
+
+func (s *Server) getIntegration(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, integ := s.store.appIntegration(params[0], params[1])
+	if integ == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, integ.out)
+}
+
+func (s *Server) updateIntegration(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.IntegrationUpdate
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, integ := s.store.appIntegration(params[0], params[1])
+	if integ == nil {
+		writeNotFound(w)
+		return
+	}
+	if in.Name == "" {
+		writeValidationError(w, "name", "field required")
+		return
+	}
+	integ.out.Name = in.Name
+	integ.out.UpdatedAt = now()
+	writeJSON(w, http.StatusOK, integ.out)
+}
+
+func (s *Server) deleteIntegration(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, integ := s.store.appIntegration(params[0], params[1])
+	if integ == nil {
+		writeNotFound(w)
+		return
+	}
+	app.removeIntegration(integ)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getIntegrationKey(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, integ := s.store.appIntegration(params[0], params[1])
+	if integ == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, svix.IntegrationKeyOut{Key: integ.key})
+}
+
+func (s *Server) rotateIntegrationKey(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, integ := s.store.appIntegration(params[0], params[1])
+	if integ == nil {
+		writeNotFound(w)
+		return
+	}
+	integ.key = newIntegrationKey()
+	writeJSON(w, http.StatusOK, svix.IntegrationKeyOut{Key: integ.key})
+}
+
+func (s *store) appIntegration(appID string, integID string) (*application, *integration) {
+	app := s.app(appID)
+	if app == nil {
+		return nil, nil
+	}
+	return app, app.integration(integID)
+}
+
+// createIntegration adds an integration, using id and key if set.
+func (a *application) createIntegration(id string, name string, key string) (*integration, *validationError) {
+	if name == "" {
+		return nil, &validationError{"name", "field required"}
+	}
+	if id == "" {
+		id = utils.NewID("integ")
+	}
+	if key == "" {
+		key = newIntegrationKey()
+	}
+	createdAt := now()
+	integ := &integration{
+		out: svix.IntegrationOut{
+			Id:        id,
+			Name:      name,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		},
+		key: key,
+	}
+	a.integrations = append(a.integrations, integ)
+	return integ, nil
+}
+
+func newIntegrationKey() string {
+	return utils.RandomString(43)
+}
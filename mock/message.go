@@ -0,0 +1,190 @@
+package mock
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/svix/svix-cli/utils"
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := newMessageFilter(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		writeNotFound(w)
+		return
+	}
+	var messages []*message
+	for _, msg := range app.messages {
+		if filter.matches(&msg.out) {
+			messages = append(messages, msg)
+		}
+	}
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.out.Id
+	}
+	indexes, iterator, done := page(r, ids, true)
+
+	out := svix.ListResponseMessageOut{
+		Data: []svix.MessageOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, messages[i].out)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) createMessage(w http.ResponseWriter, r *http.Request, params []string) {
+	var in svix.MessageIn
+	if !readJSON(w, r, &in) {
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app := s.store.app(params[0])
+	if app == nil {
+		if in.Application == nil {
+			writeNotFound(w)
+			return
+		}
+		// the application is created on the fly, with the app id in the url as its uid
+		appIn := *in.Application
+		if appIn.Uid.Get() == nil {
+			uid := params[0]
+			appIn.Uid.Set(&uid)
+		}
+		var err *validationError
+		if app, err = s.store.createApp("", &appIn); err != nil {
+			writeValidationError(w, "application."+err.field, err.msg)
+			return
+		}
+	}
+	if eventID := in.EventId.Get(); eventID != nil && app.message(*eventID) != nil {
+		writeError(w, http.StatusConflict, "conflict", "A message with this eventId already exists")
+		return
+	}
+	msg, err := app.createMessage("", &in)
+	if err != nil {
+		writeValidationError(w, err.field, err.msg)
+		return
+	}
+	s.deliver(app, msg)
+	writeJSON(w, http.StatusAccepted, msg.out)
+}
+
+func (s *Server) getMessage(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	writeJSON(w, http.StatusOK, msg.out)
+}
+
+func (s *store) appMessage(appID string, msgID string) (*application, *message) {
+	app := s.app(appID)
+	if app == nil {
+		return nil, nil
+	}
+	return app, app.message(msgID)
+}
+
+// createMessage adds a message, using id if set.
+func (a *application) createMessage(id string, in *svix.MessageIn) (*message, *validationError) {
+	if in.EventType == "" {
+		return nil, &validationError{"eventType", "field required"}
+	}
+	if in.Payload == nil {
+		return nil, &validationError{"payload", "field required"}
+	}
+	if id == "" {
+		id = utils.NewID("msg")
+	}
+	msg := &message{
+		out: svix.MessageOut{
+			Id:        id,
+			EventId:   in.EventId,
+			EventType: in.EventType,
+			Channels:  in.Channels,
+			Payload:   in.Payload,
+			Timestamp: now(),
+		},
+		nextAttempts: map[string]time.Time{},
+	}
+	a.messages = append(a.messages, msg)
+	return msg, nil
+}
+
+// messageFilter holds the filters supported by message and attempt lists.
+type messageFilter struct {
+	eventTypes map[string]bool
+	channel    string
+	before     *time.Time
+	after      *time.Time
+}
+
+func newMessageFilter(r *http.Request) (*messageFilter, error) {
+	query := r.URL.Query()
+	f := &messageFilter{
+		channel: query.Get("channel"),
+	}
+	for _, eventTypes := range query["event_types"] {
+		for _, eventType := range strings.Split(eventTypes, ",") {
+			if f.eventTypes == nil {
+				f.eventTypes = map[string]bool{}
+			}
+			f.eventTypes[eventType] = true
+		}
+	}
+	var err error
+	if f.before, err = queryTime(r, "before"); err != nil {
+		return nil, err
+	}
+	if f.after, err = queryTime(r, "after"); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *messageFilter) matches(msg *svix.MessageOut) bool {
+	if f.eventTypes != nil && !f.eventTypes[msg.EventType] {
+		return false
+	}
+	if f.channel != "" && !containsString(msg.Channels, f.channel) {
+		return false
+	}
+	if f.before != nil && !msg.Timestamp.Before(*f.before) {
+		return false
+	}
+	if f.after != nil && !msg.Timestamp.After(*f.after) {
+		return false
+	}
+	return true
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
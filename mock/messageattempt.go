@@ -0,0 +1,361 @@
+package mock
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// attemptFilter holds the filters supported by attempt lists, times are
+// compared against the attempts' timestamps.
+type attemptFilter struct {
+	*messageFilter
+	status     *svix.MessageStatus
+	endpointID string
+}
+
+func newAttemptFilter(r *http.Request) (*attemptFilter, error) {
+	msgFilter, err := newMessageFilter(r)
+	if err != nil {
+		return nil, err
+	}
+	f := &attemptFilter{
+		messageFilter: msgFilter,
+		endpointID:    r.URL.Query().Get("endpoint_id"),
+	}
+	if f.status, err = queryStatus(r); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// queryStatus parses the status query parameter, 0 for success, 1 pending, 2 fail and 3 sending.
+func queryStatus(r *http.Request) (*svix.MessageStatus, error) {
+	raw := r.URL.Query().Get("status")
+	if raw == "" {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 || n > 3 {
+		return nil, fmt.Errorf("invalid status: %s", raw)
+	}
+	status := svix.MessageStatus(n)
+	return &status, nil
+}
+
+func (f *attemptFilter) matches(msg *svix.MessageOut, attempt *svix.MessageAttemptOut) bool {
+	if f.eventTypes != nil && !f.eventTypes[msg.EventType] {
+		return false
+	}
+	if f.channel != "" && !containsString(msg.Channels, f.channel) {
+		return false
+	}
+	if f.before != nil && !attempt.Timestamp.Before(*f.before) {
+		return false
+	}
+	if f.after != nil && !attempt.Timestamp.After(*f.after) {
+		return false
+	}
+	if f.status != nil && attempt.Status != *f.status {
+		return false
+	}
+	if f.endpointID != "" && attempt.EndpointId != f.endpointID {
+		return false
+	}
+	return true
+}
+
+func (s *Server) listAttemptsByMessage(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := newAttemptFilter(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	if filter.endpointID != "" {
+		// the endpoint can be given by uid
+		if ep := app.endpoint(filter.endpointID); ep != nil {
+			filter.endpointID = ep.out.Id
+		}
+	}
+	var attempts []*svix.MessageAttemptOut
+	for _, attempt := range msg.attempts {
+		if filter.matches(&msg.out, attempt) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	writeAttempts(w, r, attempts)
+}
+
+func (s *Server) listAttemptsByEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := newAttemptFilter(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	filter.endpointID = ep.out.Id
+	var attempts []*svix.MessageAttemptOut
+	for _, msg := range app.messages {
+		for _, attempt := range msg.attempts {
+			if filter.matches(&msg.out, attempt) {
+				attempts = append(attempts, attempt)
+			}
+		}
+	}
+	writeAttempts(w, r, attempts)
+}
+
+func writeAttempts(w http.ResponseWriter, r *http.Request, attempts []*svix.MessageAttemptOut) {
+	ids := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		ids[i] = attempt.Id
+	}
+	indexes, iterator, done := page(r, ids, true)
+
+	out := svix.ListResponseMessageAttemptOut{
+		Data: []svix.MessageAttemptOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, *attempts[i])
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) getAttempt(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	_, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	for _, attempt := range msg.attempts {
+		if attempt.Id == params[2] {
+			writeJSON(w, http.StatusOK, attempt)
+			return
+		}
+	}
+	writeNotFound(w)
+}
+
+// listAttemptedDestinations lists the endpoints a message was attempted to, with
+// the status of their latest attempt.
+func (s *Server) listAttemptedDestinations(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	var endpoints []*endpoint
+	for _, ep := range app.endpoints {
+		if msg.lastAttempt(ep.out.Id) != nil {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	ids := make([]string, len(endpoints))
+	for i, ep := range endpoints {
+		ids[i] = ep.out.Id
+	}
+	indexes, iterator, done := page(r, ids, false)
+
+	out := svix.ListResponseMessageEndpointOut{
+		Data: []svix.MessageEndpointOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		ep := endpoints[i].out
+		status, nextAttempt := msg.status(ep.Id)
+		dest := svix.MessageEndpointOut{
+			Id:          ep.Id,
+			Uid:         ep.Uid,
+			Url:         ep.Url,
+			Description: ep.Description,
+			RateLimit:   ep.RateLimit,
+			Channels:    ep.Channels,
+			FilterTypes: ep.FilterTypes,
+			Disabled:    ep.Disabled,
+			Version:     ep.Version,
+			CreatedAt:   ep.CreatedAt,
+			UpdatedAt:   ep.UpdatedAt,
+			Status:      status,
+		}
+		dest.NextAttempt.Set(nextAttempt)
+		out.Data = append(out.Data, dest)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) listAttemptsForEndpoint(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := newAttemptFilter(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	ep := app.endpoint(params[2])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	filter.endpointID = ep.out.Id
+	var attempts []*svix.MessageAttemptOut
+	for _, attempt := range msg.attempts {
+		if filter.matches(&msg.out, attempt) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	ids := make([]string, len(attempts))
+	for i, attempt := range attempts {
+		ids[i] = attempt.Id
+	}
+	indexes, iterator, done := page(r, ids, true)
+
+	out := svix.ListResponseMessageAttemptEndpointOut{
+		Data: []svix.MessageAttemptEndpointOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, svix.MessageAttemptEndpointOut(*attempts[i]))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// listAttemptedMessages lists the messages attempted to an endpoint, with the
+// status of their latest attempt.
+func (s *Server) listAttemptedMessages(w http.ResponseWriter, r *http.Request, params []string) {
+	filter, err := newMessageFilter(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+	status, err := queryStatus(r)
+	if err != nil {
+		writeValidationError(w, "query", err.Error())
+		return
+	}
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, ep := s.store.appEndpoint(params[0], params[1])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	var messages []svix.EndpointMessageOut
+	for _, msg := range app.messages {
+		if msg.lastAttempt(ep.out.Id) == nil || !filter.matches(&msg.out) {
+			continue
+		}
+		msgStatus, nextAttempt := msg.status(ep.out.Id)
+		if status != nil && msgStatus != *status {
+			continue
+		}
+		epMsg := svix.EndpointMessageOut{
+			Id:        msg.out.Id,
+			EventId:   msg.out.EventId,
+			EventType: msg.out.EventType,
+			Channels:  msg.out.Channels,
+			Payload:   msg.out.Payload,
+			Timestamp: msg.out.Timestamp,
+			Status:    msgStatus,
+		}
+		epMsg.NextAttempt.Set(nextAttempt)
+		messages = append(messages, epMsg)
+	}
+	ids := make([]string, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.Id
+	}
+	indexes, iterator, done := page(r, ids, true)
+
+	out := svix.ListResponseEndpointMessageOut{
+		Data: []svix.EndpointMessageOut{},
+		Done: done,
+	}
+	out.Iterator.Set(iterator)
+	for _, i := range indexes {
+		out.Data = append(out.Data, messages[i])
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (s *Server) resendMessage(w http.ResponseWriter, r *http.Request, params []string) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+
+	app, msg := s.store.appMessage(params[0], params[1])
+	if msg == nil {
+		writeNotFound(w)
+		return
+	}
+	ep := app.endpoint(params[2])
+	if ep == nil {
+		writeNotFound(w)
+		return
+	}
+	if ep.disabled() {
+		writeError(w, http.StatusConflict, "endpoint_disabled", "The endpoint is disabled")
+		return
+	}
+	go s.attempt(app.out.Id, msg.out.Id, ep.out.Id, true)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// lastAttempt returns the message's latest attempt to an endpoint, or nil if it hasn't been attempted.
+func (m *message) lastAttempt(endpointID string) *svix.MessageAttemptOut {
+	for i := len(m.attempts) - 1; i >= 0; i-- {
+		if m.attempts[i].EndpointId == endpointID {
+			return m.attempts[i]
+		}
+	}
+	return nil
+}
+
+// status returns the delivery status of the message to an endpoint, which is
+// pending while a retry is scheduled, and when that retry is.
+func (m *message) status(endpointID string) (svix.MessageStatus, *time.Time) {
+	if next, ok := m.nextAttempts[endpointID]; ok {
+		return 1, &next
+	}
+	if attempt := m.lastAttempt(endpointID); attempt != nil {
+		return attempt.Status, nil
+	}
+	return 1, nil
+}
@@ -0,0 +1,334 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/svix/svix-cli/sender"
+)
+
+const (
+	DefaultServerAddr     = "localhost:8071"
+	apiPrefix             = "/api/v1/"
+	defaultLimit          = 50
+	maxLimit              = 250
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// Server is an in-memory implementation of the parts of the Svix API used by the cli,
+// for scripting and testing against without a real Svix server.
+type Server struct {
+	addr          string
+	store         *store
+	routes        []*route
+	httpClient    *http.Client
+	retrySchedule []time.Duration
+
+	// ctx is canceled when the server shuts down, stopping pending retries
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+type ServerOptions struct {
+	// Fixtures seed the server's state
+	Fixtures *Fixtures
+	// RetrySchedule is the delay before each retry of a failed delivery, defaults to Svix's schedule
+	RetrySchedule []time.Duration
+}
+
+type route struct {
+	method  string
+	pattern []string
+	handle  func(w http.ResponseWriter, r *http.Request, params []string)
+}
+
+func NewServer(addr string, opts *ServerOptions) (*Server, error) {
+	retrySchedule := sender.RetrySchedule
+	if opts != nil && opts.RetrySchedule != nil {
+		retrySchedule = opts.RetrySchedule
+	}
+
+	s := &Server{
+		addr:  addr,
+		store: newStore(),
+		httpClient: &http.Client{
+			Timeout: sender.Timeout,
+		},
+		retrySchedule: retrySchedule,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	s.routes = s.makeRoutes()
+	if opts != nil && opts.Fixtures != nil {
+		if err := s.store.seed(opts.Fixtures); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func (s *Server) makeRoutes() []*route {
+	routes := []struct {
+		method  string
+		pattern string
+		handle  func(w http.ResponseWriter, r *http.Request, params []string)
+	}{
+		{http.MethodGet, "app", s.listApplications},
+		{http.MethodPost, "app", s.createApplication},
+		{http.MethodGet, "app/*", s.getApplication},
+		{http.MethodPut, "app/*", s.updateApplication},
+		{http.MethodDelete, "app/*", s.deleteApplication},
+
+		{http.MethodGet, "app/*/endpoint", s.listEndpoints},
+		{http.MethodPost, "app/*/endpoint", s.createEndpoint},
+		{http.MethodGet, "app/*/endpoint/*", s.getEndpoint},
+		{http.MethodPut, "app/*/endpoint/*", s.updateEndpoint},
+		{http.MethodDelete, "app/*/endpoint/*", s.deleteEndpoint},
+		{http.MethodGet, "app/*/endpoint/*/secret", s.getEndpointSecret},
+		{http.MethodPost, "app/*/endpoint/*/secret/rotate", s.rotateEndpointSecret},
+		{http.MethodGet, "app/*/endpoint/*/headers", s.getEndpointHeaders},
+		{http.MethodPut, "app/*/endpoint/*/headers", s.updateEndpointHeaders},
+		{http.MethodPatch, "app/*/endpoint/*/headers", s.patchEndpointHeaders},
+
+		{http.MethodGet, "event-type", s.listEventTypes},
+		{http.MethodPost, "event-type", s.createEventType},
+		{http.MethodGet, "event-type/*", s.getEventType},
+		{http.MethodPut, "event-type/*", s.updateEventType},
+		{http.MethodDelete, "event-type/*", s.deleteEventType},
+
+		{http.MethodGet, "app/*/msg", s.listMessages},
+		{http.MethodPost, "app/*/msg", s.createMessage},
+		{http.MethodGet, "app/*/msg/*", s.getMessage},
+
+		{http.MethodGet, "app/*/attempt/msg/*", s.listAttemptsByMessage},
+		{http.MethodGet, "app/*/attempt/endpoint/*", s.listAttemptsByEndpoint},
+		{http.MethodGet, "app/*/msg/*/attempt", s.listAttemptsByMessage},
+		{http.MethodGet, "app/*/msg/*/attempt/*", s.getAttempt},
+		{http.MethodGet, "app/*/msg/*/endpoint", s.listAttemptedDestinations},
+		{http.MethodGet, "app/*/msg/*/endpoint/*/attempt", s.listAttemptsForEndpoint},
+		{http.MethodPost, "app/*/msg/*/endpoint/*/resend", s.resendMessage},
+		{http.MethodGet, "app/*/endpoint/*/msg", s.listAttemptedMessages},
+
+		{http.MethodGet, "app/*/integration", s.listIntegrations},
+		{http.MethodPost, "app/*/integration", s.createIntegration},
+		{http.MethodGet, "app/*/integration/*", s.getIntegration},
+		{http.MethodPut, "app/*/integration/*", s.updateIntegration},
+		{http.MethodDelete, "app/*/integration/*", s.deleteIntegration},
+		{http.MethodGet, "app/*/integration/*/key", s.getIntegrationKey},
+		{http.MethodPost, "app/*/integration/*/key/rotate", s.rotateIntegrationKey},
+
+		{http.MethodPost, "auth/app-portal-access/*", s.appPortalAccess},
+		{http.MethodPost, "auth/dashboard-access/*", s.appPortalAccess},
+		{http.MethodPost, "auth/logout", s.logout},
+	}
+
+	out := make([]*route, 0, len(routes))
+	for _, r := range routes {
+		out = append(out, &route{
+			method:  r.method,
+			pattern: strings.Split(r.pattern, "/"),
+			handle:  r.handle,
+		})
+	}
+	return out
+}
+
+func (s *Server) Handler() http.Handler {
+	return http.HandlerFunc(s.serveHTTP)
+}
+
+// ListenAndServe serves the api until the context is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	defer s.cancel()
+
+	srv := &http.Server{
+		Addr:    s.addr,
+		Handler: s.Handler(),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.ListenAndServe()
+	}()
+
+	fmt.Printf(`Mock Svix API server is now running at
+http://%s/
+
+Point the cli at it by running:
+export SVIX_SERVER_URL=http://%s SVIX_AUTH_TOKEN=testsk_mock
+`, s.addr, s.addr)
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.URL.Path, apiPrefix) {
+		writeError(w, http.StatusNotFound, "not_found", "Not found")
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		writeError(w, http.StatusUnauthorized, "authentication_failed", "Invalid token")
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix), "/"), "/")
+	pathMatched := false
+	for _, route := range s.routes {
+		params, ok := matchPattern(route.pattern, parts)
+		if !ok {
+			continue
+		}
+		if route.method != r.Method {
+			pathMatched = true
+			continue
+		}
+		route.handle(w, r, params)
+		return
+	}
+	if pathMatched {
+		writeError(w, http.StatusMethodNotAllowed, "method_not_allowed", "Method not allowed")
+		return
+	}
+	writeError(w, http.StatusNotFound, "not_found", "Not found")
+}
+
+// matchPattern matches path segments against a pattern, returning the segments matched by *.
+func matchPattern(pattern []string, parts []string) ([]string, bool) {
+	if len(pattern) != len(parts) {
+		return nil, false
+	}
+	var params []string
+	for i, segment := range pattern {
+		if segment == "*" {
+			if parts[i] == "" {
+				return nil, false
+			}
+			params = append(params, parts[i])
+		} else if segment != parts[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+// readJSON decodes the request body into v, an empty body leaves v unchanged.
+func readJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+		writeValidationError(w, "body", err.Error())
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, detail string) {
+	writeJSON(w, status, map[string]string{
+		"code":   code,
+		"detail": detail,
+	})
+}
+
+func writeNotFound(w http.ResponseWriter) {
+	writeError(w, http.StatusNotFound, "not_found", "Entity not found")
+}
+
+type validationError struct {
+	field string
+	msg   string
+}
+
+func writeValidationError(w http.ResponseWriter, field string, msg string) {
+	writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+		"detail": []map[string]interface{}{{
+			"loc":  []string{"body", field},
+			"msg":  msg,
+			"type": "value_error",
+		}},
+	})
+}
+
+// page returns the indexes of the items on the requested page of a list, ids
+// are the ids of all the list's items in ascending order. Iterators are the
+// id of the last item of the previous page.
+func page(r *http.Request, ids []string, descendingByDefault bool) (indexes []int, iterator *string, done bool) {
+	limit := defaultLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			limit = n
+		}
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	desc := descendingByDefault
+	switch r.URL.Query().Get("order") {
+	case "ascending":
+		desc = false
+	case "descending":
+		desc = true
+	}
+	order := make([]int, len(ids))
+	for i := range ids {
+		if desc {
+			order[i] = len(ids) - 1 - i
+		} else {
+			order[i] = i
+		}
+	}
+
+	start := 0
+	if it := r.URL.Query().Get("iterator"); it != "" {
+		for pos, i := range order {
+			if ids[i] == it {
+				start = pos + 1
+				break
+			}
+		}
+	}
+	end := start + limit
+	if end >= len(order) {
+		end = len(order)
+		done = true
+	}
+	indexes = order[start:end]
+	if len(indexes) > 0 {
+		last := ids[indexes[len(indexes)-1]]
+		iterator = &last
+	}
+	return indexes, iterator, done
+}
+
+func queryTime(r *http.Request, name string) (*time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", name, err)
+	}
+	return &t, nil
+}
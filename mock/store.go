@@ -0,0 +1,142 @@
+package mock
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"sort"
+	"sync"
+	"time"
+
+	svix "github.com/svix/svix-webhooks/go"
+)
+
+// store holds the server's state, handlers must hold mu while using it.
+type store struct {
+	mu         sync.Mutex
+	apps       []*application
+	eventTypes map[string]*svix.EventTypeOut
+}
+
+type application struct {
+	out          svix.ApplicationOut
+	endpoints    []*endpoint
+	messages     []*message
+	integrations []*integration
+}
+
+type endpoint struct {
+	out     svix.EndpointOut
+	secret  string
+	headers map[string]string
+}
+
+type message struct {
+	out      svix.MessageOut
+	attempts []*svix.MessageAttemptOut
+	// nextAttempts holds when failed deliveries will be retried, by endpoint id
+	nextAttempts map[string]time.Time
+}
+
+type integration struct {
+	out svix.IntegrationOut
+	key string
+}
+
+func newStore() *store {
+	return &store{
+		eventTypes: map[string]*svix.EventTypeOut{},
+	}
+}
+
+// app finds an application by id or uid.
+func (s *store) app(idOrUID string) *application {
+	for _, app := range s.apps {
+		if app.out.Id == idOrUID || uidEquals(app.out.Uid.Get(), idOrUID) {
+			return app
+		}
+	}
+	return nil
+}
+
+func (s *store) removeApp(app *application) {
+	for i, a := range s.apps {
+		if a == app {
+			s.apps = append(s.apps[:i], s.apps[i+1:]...)
+			return
+		}
+	}
+}
+
+func (s *store) sortedEventTypes() []*svix.EventTypeOut {
+	eventTypes := make([]*svix.EventTypeOut, 0, len(s.eventTypes))
+	for _, et := range s.eventTypes {
+		eventTypes = append(eventTypes, et)
+	}
+	sort.Slice(eventTypes, func(i, j int) bool {
+		return eventTypes[i].Name < eventTypes[j].Name
+	})
+	return eventTypes
+}
+
+// endpoint finds an endpoint by id or uid.
+func (a *application) endpoint(idOrUID string) *endpoint {
+	for _, ep := range a.endpoints {
+		if ep.out.Id == idOrUID || uidEquals(ep.out.Uid.Get(), idOrUID) {
+			return ep
+		}
+	}
+	return nil
+}
+
+func (a *application) removeEndpoint(ep *endpoint) {
+	for i, e := range a.endpoints {
+		if e == ep {
+			a.endpoints = append(a.endpoints[:i], a.endpoints[i+1:]...)
+			return
+		}
+	}
+}
+
+// message finds a message by id or event id.
+func (a *application) message(idOrEventID string) *message {
+	for _, msg := range a.messages {
+		if msg.out.Id == idOrEventID || uidEquals(msg.out.EventId.Get(), idOrEventID) {
+			return msg
+		}
+	}
+	return nil
+}
+
+func (a *application) integration(id string) *integration {
+	for _, integ := range a.integrations {
+		if integ.out.Id == id {
+			return integ
+		}
+	}
+	return nil
+}
+
+func (a *application) removeIntegration(integ *integration) {
+	for i, in := range a.integrations {
+		if in == integ {
+			a.integrations = append(a.integrations[:i], a.integrations[i+1:]...)
+			return
+		}
+	}
+}
+
+func uidEquals(uid *string, value string) bool {
+	return uid != nil && *uid == value
+}
+
+func now() time.Time {
+	return time.Now().UTC()
+}
+
+func newEndpointSecret() string {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return "whsec_" + base64.StdEncoding.EncodeToString(b)
+}
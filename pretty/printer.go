@@ -25,14 +25,20 @@ func NewPrinter(opts *PrinterOptions) *Printer {
 }
 
 func (p *Printer) Write(b []byte) (n int, err error) {
+	b = p.Format(b)
+	fmt.Println(string(b))
+	return len(b), err
+}
+
+// Format pretty prints (and colors if enabled) b if it is json, otherwise b is returned unchanged.
+func (p *Printer) Format(b []byte) []byte {
 	if isJSON(b) {
 		b = prettyJson.Pretty(b)
 		if p.opts != nil && p.opts.Color {
 			b = prettyJson.Color(b, nil)
 		}
 	}
-	fmt.Println(string(b))
-	return len(b), err
+	return b
 }
 
 func (p *Printer) Print(a ...interface{}) {
@@ -59,13 +65,7 @@ func (p *Printer) Print(a ...interface{}) {
 			b = buf.Bytes()
 		}
 
-		if isJSON(b) {
-			b = prettyJson.Pretty(b)
-			if p.opts != nil && p.opts.Color {
-				b = prettyJson.Color(b, nil)
-			}
-		}
-		fmt.Println(string(b))
+		fmt.Println(string(p.Format(b)))
 	}
 }
 
@@ -0,0 +1,47 @@
+package pretty
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+const progressBarWidth = 30
+
+// ProgressBar draws a progress bar on a single, redrawn line of a terminal.
+type ProgressBar struct {
+	w     io.Writer
+	label string
+	mu    sync.Mutex
+}
+
+func NewProgressBar(w io.Writer, label string) *ProgressBar {
+	return &ProgressBar{
+		w:     w,
+		label: label,
+	}
+}
+
+// Set redraws the bar with done out of total items, followed by suffix.
+func (b *ProgressBar) Set(done int, total int, suffix string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	filled := progressBarWidth
+	if total > 0 {
+		filled = progressBarWidth * done / total
+	}
+	bar := strings.Repeat("=", filled)
+	if filled < progressBarWidth {
+		bar += ">" + strings.Repeat(" ", progressBarWidth-filled-1)
+	}
+	fmt.Fprintf(b.w, "\r\033[K%s [%s] %d/%d %s", b.label, bar, done, total, suffix)
+}
+
+// Finish ends the bar's line, so following output is written below it.
+func (b *ProgressBar) Finish() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fmt.Fprintln(b.w)
+}
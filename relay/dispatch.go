@@ -0,0 +1,152 @@
+package relay
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// dispatcher queues incoming messages, starting them in order while limiting
+// how many are handled concurrently and how often a new one is started.
+type dispatcher struct {
+	mu     sync.Mutex
+	queue  [][]byte
+	notify chan struct{}
+	// slots is nil when concurrency isn't limited
+	slots chan struct{}
+	// bucket is nil when the rate isn't limited
+	bucket *tokenBucket
+	handle func(packet []byte)
+}
+
+func newDispatcher(maxConcurrency int, rateLimit float64, rateBurst int, handle func(packet []byte)) *dispatcher {
+	d := &dispatcher{
+		notify: make(chan struct{}, 1),
+		handle: handle,
+	}
+	if maxConcurrency > 0 {
+		d.slots = make(chan struct{}, maxConcurrency)
+	}
+	if rateLimit > 0 {
+		d.bucket = newTokenBucket(rateLimit, rateBurst)
+	}
+	return d
+}
+
+func (d *dispatcher) enqueue(packet []byte) {
+	d.mu.Lock()
+	d.queue = append(d.queue, packet)
+	d.mu.Unlock()
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run starts queued messages until stop is closed.
+func (d *dispatcher) run(stop <-chan struct{}) {
+	for {
+		if !d.waitForMessage(stop) {
+			return
+		}
+		if d.slots != nil {
+			select {
+			case d.slots <- struct{}{}:
+			case <-stop:
+				return
+			}
+		}
+		if d.bucket != nil {
+			if delay := d.bucket.take(time.Now()); delay > 0 {
+				select {
+				case <-time.After(delay):
+				case <-stop:
+					return
+				}
+			}
+		}
+
+		d.mu.Lock()
+		packet := d.queue[0]
+		d.queue = d.queue[1:]
+		queued := len(d.queue)
+		d.mu.Unlock()
+
+		if queued > 0 {
+			color.Yellow("   %d message(s) queued", queued)
+		}
+		go func() {
+			defer d.release()
+			d.handle(packet)
+		}()
+	}
+}
+
+// acquire blocks until a concurrency slot is free, for requests started
+// outside of run, like the copies of a message sent to other targets.
+func (d *dispatcher) acquire() {
+	if d.slots != nil {
+		d.slots <- struct{}{}
+	}
+}
+
+func (d *dispatcher) release() {
+	if d.slots != nil {
+		<-d.slots
+	}
+}
+
+// waitForMessage returns true once a message is queued, or false if stop is closed first.
+func (d *dispatcher) waitForMessage(stop <-chan struct{}) bool {
+	for {
+		d.mu.Lock()
+		n := len(d.queue)
+		d.mu.Unlock()
+		if n > 0 {
+			return true
+		}
+		select {
+		case <-d.notify:
+		case <-stop:
+			return false
+		}
+	}
+}
+
+// tokenBucket refills rate tokens per second up to burst, starting a message takes a token.
+// Only the dispatcher may take from it.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// take takes a token at now, returning how long to wait before the message may start.
+func (b *tokenBucket) take(now time.Time) time.Duration {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	var delay time.Duration
+	if b.tokens < 1 {
+		delay = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.tokens = 1
+		b.last = now.Add(delay)
+	}
+	b.tokens--
+	return delay
+}
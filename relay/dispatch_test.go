@@ -0,0 +1,174 @@
+package relay
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(2, 3)
+	b.last = start
+
+	// the burst is available at once, then a token every 500ms
+	steps := []struct {
+		at   time.Duration
+		want time.Duration
+	}{
+		{at: 0, want: 0},
+		{at: 0, want: 0},
+		{at: 0, want: 0},
+		{at: 0, want: 500 * time.Millisecond},
+		{at: 500 * time.Millisecond, want: 500 * time.Millisecond},
+		{at: 1250 * time.Millisecond, want: 250 * time.Millisecond},
+		// idle long enough to refill the burst, but not more
+		{at: 10 * time.Second, want: 0},
+		{at: 10 * time.Second, want: 0},
+		{at: 10 * time.Second, want: 0},
+		{at: 10 * time.Second, want: 500 * time.Millisecond},
+	}
+	for i, step := range steps {
+		if got := b.take(start.Add(step.at)); got != step.want {
+			t.Errorf("take %d at %v waits %v, want %v", i+1, step.at, got, step.want)
+		}
+	}
+}
+
+func TestTokenBucketMinimumBurst(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(10, 0)
+	b.last = start
+	if got := b.take(start); got != 0 {
+		t.Errorf("first take waits %v, want 0", got)
+	}
+	if got := b.take(start); got != 100*time.Millisecond {
+		t.Errorf("second take waits %v, want 100ms", got)
+	}
+}
+
+// runDispatcher enqueues n messages and waits until they're all handled,
+// returning the order they were started in and the most handled at once.
+func runDispatcher(t *testing.T, maxConcurrency int, rateLimit float64, n int) ([]string, int) {
+	t.Helper()
+	var mu sync.Mutex
+	var order []string
+	running, peak := 0, 0
+	var wg sync.WaitGroup
+	wg.Add(n)
+	d := newDispatcher(maxConcurrency, rateLimit, 1, func(packet []byte) {
+		defer wg.Done()
+		mu.Lock()
+		order = append(order, string(packet))
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	})
+	stop := make(chan struct{})
+	defer close(stop)
+	go d.run(stop)
+	for i := 0; i < n; i++ {
+		d.enqueue([]byte(fmt.Sprint(i)))
+	}
+	wg.Wait()
+	return order, peak
+}
+
+func TestDispatcherSerial(t *testing.T) {
+	order, peak := runDispatcher(t, 1, 0, 20)
+	if peak != 1 {
+		t.Errorf("handled %d messages at once, want 1", peak)
+	}
+	for i, packet := range order {
+		if packet != fmt.Sprint(i) {
+			t.Fatalf("started messages in order %v, want the order they were received", order)
+		}
+	}
+}
+
+func TestDispatcherMaxConcurrency(t *testing.T) {
+	_, peak := runDispatcher(t, 3, 0, 20)
+	if peak > 3 {
+		t.Errorf("handled %d messages at once, want at most 3", peak)
+	}
+}
+
+func TestDispatcherRateLimit(t *testing.T) {
+	start := time.Now()
+	runDispatcher(t, 0, 100, 6)
+	// the first message uses the burst, the other 5 wait 10ms each
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("handled 6 messages in %v at 100/s, want at least 50ms", elapsed)
+	}
+}
+
+func TestDispatcherStop(t *testing.T) {
+	d := newDispatcher(1, 0, 1, func(packet []byte) {})
+	// hold the only slot, so run blocks waiting for one
+	d.acquire()
+	d.enqueue([]byte("blocked"))
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		d.run(stop)
+		close(stopped)
+	}()
+	close(stop)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("run didn't return after stop")
+	}
+}
+
+func TestForwardCopiesTakeSlots(t *testing.T) {
+	requests := make(chan string, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests <- r.URL.Path
+	}))
+	defer server.Close()
+
+	route, err := ParseRoute(fmt.Sprintf("default=%s/primary,%s/copy", server.URL, server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{
+		httpClient: NewLocalHTTPClient(time.Second),
+		dispatcher: newDispatcher(1, 0, 1, nil),
+	}
+	// the message being forwarded holds the only slot
+	c.dispatcher.acquire()
+	res, err := c.forward(route, IncomingMessageEventData{Method: "POST", Headers: map[string]string{}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if path := <-requests; path != "/primary" {
+		t.Fatalf("first request to %s, want /primary", path)
+	}
+	select {
+	case path := <-requests:
+		t.Fatalf("request to %s while the slot was taken", path)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.dispatcher.release()
+	select {
+	case path := <-requests:
+		if path != "/copy" {
+			t.Errorf("request to %s, want /copy", path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("copy wasn't sent once the slot was free")
+	}
+}
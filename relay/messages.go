@@ -34,6 +34,8 @@ type IncomingMessageEventData struct {
 	Headers map[string]string `json:"headers"`
 	Body    string            `json:"body"`
 	Method  string            `json:"method"`
+	// Path is the path requested after the relay token, only sent by `svix relay serve`
+	Path string `json:"path,omitempty"`
 }
 
 type OutgoingMessageStart struct {
@@ -0,0 +1,151 @@
+package relay
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+const maxRecordSize = 16 << 20 // 16MiB
+
+// Record is a relayed request and the response of the local server,
+// as stored by `svix listen --record` in a JSONL file. The request body is
+// stored base64 encoded, so that binary bodies are replayed byte for byte.
+type Record struct {
+	ID        string            `json:"id"`
+	Timestamp time.Time         `json:"timestamp"`
+	Method    string            `json:"method"`
+	Path      string            `json:"path,omitempty"`
+	Headers   map[string]string `json:"headers"`
+	Body      []byte            `json:"bodyBase64"`
+	Verified  *bool             `json:"verified,omitempty"`
+	Response  *RecordResponse   `json:"response,omitempty"`
+	Error     string            `json:"error,omitempty"`
+}
+
+type RecordResponse struct {
+	Status    int               `json:"status"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+	LatencyMs int64             `json:"latencyMs"`
+}
+
+func newRecord(msg *IncomingMessageEventData, body []byte, receivedAt time.Time) *Record {
+	return &Record{
+		ID:        msg.ID,
+		Timestamp: receivedAt,
+		Method:    msg.Method,
+		Path:      msg.Path,
+		Headers:   msg.Headers,
+		Body:      body,
+	}
+}
+
+// UnmarshalJSON also reads records written before bodies were base64 encoded,
+// which have them as text.
+func (r *Record) UnmarshalJSON(data []byte) error {
+	type record Record
+	aux := struct {
+		*record
+		TextBody *string `json:"body"`
+	}{record: (*record)(r)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if r.Body == nil && aux.TextBody != nil {
+		r.Body = []byte(*aux.TextBody)
+	}
+	return nil
+}
+
+func (r *Record) setResponse(res *OutgoingMessageEventData, latency time.Duration) {
+	body, _ := base64.StdEncoding.DecodeString(res.Body)
+	r.Response = &RecordResponse{
+		Status:    res.Status,
+		Headers:   res.Headers,
+		Body:      string(body),
+		LatencyMs: latency.Milliseconds(),
+	}
+}
+
+// EventType returns the event type of the request's json body, if any.
+func (r *Record) EventType() string {
+	return eventTypeFromBody(r.Body)
+}
+
+func (r *Record) message() IncomingMessageEventData {
+	return IncomingMessageEventData{
+		ID:      r.ID,
+		Headers: r.Headers,
+		Body:    base64.StdEncoding.EncodeToString(r.Body),
+		Method:  r.Method,
+		Path:    r.Path,
+	}
+}
+
+// Request creates a new request to target, identical to the one originally relayed.
+func (r *Record) Request(target *url.URL) *http.Request {
+	return newLocalRequest(target, r.Method, r.Headers, r.Body)
+}
+
+// Recorder appends records to a JSONL file, it is safe for concurrent use.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewRecorder(fileName string) (*Recorder, error) {
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(file)
+	enc.SetEscapeHTML(false)
+	return &Recorder{
+		file: file,
+		enc:  enc,
+	}, nil
+}
+
+func (r *Recorder) Write(rec *Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.enc.Encode(rec)
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// ReadRecords reads all records from a JSONL file written by a Recorder.
+func ReadRecords(reader io.Reader) ([]*Record, error) {
+	var records []*Record
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), maxRecordSize)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var rec Record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("invalid record on line %d: %s", line, err)
+		}
+		records = append(records, &rec)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
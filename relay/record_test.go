@@ -0,0 +1,103 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordRoundTrip(t *testing.T) {
+	// gzip magic bytes and invalid utf-8, which json strings can't hold
+	body := []byte{0x1f, 0x8b, 0x08, 0x00, 0xff, 0xfe, 'a', 0x80, 0x00}
+	msg := &IncomingMessageEventData{
+		ID:      "msg_1",
+		Method:  "POST",
+		Path:    "/hooks",
+		Headers: map[string]string{"Content-Encoding": "gzip"},
+	}
+	rec := newRecord(msg, body, time.Unix(1700000000, 0))
+
+	fileName := filepath.Join(t.TempDir(), "requests.jsonl")
+	recorder, err := NewRecorder(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := ReadRecords(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("read %d records, want 1", len(records))
+	}
+	got := records[0]
+	if !bytes.Equal(got.Body, body) {
+		t.Errorf("body = %x, want %x", got.Body, body)
+	}
+	if got.ID != "msg_1" || got.Method != "POST" || got.Path != "/hooks" {
+		t.Errorf("record = %+v, want the recorded request", got)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(got.message().Body)
+	if err != nil || !bytes.Equal(decoded, body) {
+		t.Errorf("message body = %x, want %x", decoded, body)
+	}
+
+	target, _ := url.Parse("http://localhost:8000/webhook/")
+	req := got.Request(target)
+	sent, err := io.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(sent, body) || req.ContentLength != int64(len(body)) {
+		t.Errorf("replayed body = %x (length %d), want %x", sent, req.ContentLength, body)
+	}
+}
+
+func TestReadRecordsTextBody(t *testing.T) {
+	// records written before bodies were base64 encoded
+	jsonl := `{"id":"msg_1","timestamp":"2023-01-01T00:00:00Z","method":"POST","headers":{},"body":"{\"type\":\"invoice.paid\"}"}
+
+{"id":"msg_2","timestamp":"2023-01-01T00:00:00Z","method":"POST","headers":{},"bodyBase64":"aGVsbG8="}
+`
+	records, err := ReadRecords(strings.NewReader(jsonl))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("read %d records, want 2", len(records))
+	}
+	if string(records[0].Body) != `{"type":"invoice.paid"}` {
+		t.Errorf("text body = %q", records[0].Body)
+	}
+	if records[0].EventType() != "invoice.paid" {
+		t.Errorf("event type = %q, want invoice.paid", records[0].EventType())
+	}
+	if string(records[1].Body) != "hello" {
+		t.Errorf("base64 body = %q, want hello", records[1].Body)
+	}
+}
+
+func TestReadRecordsInvalid(t *testing.T) {
+	_, err := ReadRecords(strings.NewReader("{\"id\":\"msg_1\"}\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("err = %v, want an invalid record on line 2", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"net/url"
 	"strings"
@@ -16,7 +17,9 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/gorilla/websocket"
+	"github.com/svix/svix-cli/capture"
 	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/signature"
 )
 
 // Defaults
@@ -27,16 +30,30 @@ const (
 	pongWait       = 10 * time.Second
 	pingPeriod     = (pongWait * 2) / 10
 	writeWait      = 10 * time.Second
+
+	// CLIGeneratedHeader marks responses created by the cli instead of the local server
+	CLIGeneratedHeader = "Svix-Cli-Generated"
+
+	defaultDrainTimeout = 10 * time.Second
+	closeTimeout        = 2 * time.Second
 )
 
 type Client struct {
 	token              string
 	websocketURL       string
-	localURL           *url.URL
+	routes             []*Route
 	receiveURLTemplate string
 	dialer             *websocket.Dialer
 	httpClient         *http.Client
 	logging            bool
+	recorder           *Recorder
+	verifyKey          *signature.Key
+	rejectUnverified   bool
+	onConnect          func(receiveURL string)
+	onRecord           func(rec *Record)
+	transformations    *Transformations
+	drainTimeout       time.Duration
+	dispatcher         *dispatcher
 
 	conn              *websocket.Conn
 	stopRead          chan struct{}
@@ -47,47 +64,124 @@ type Client struct {
 	sendChan chan *OutgoingMessageEvent
 	recChan  chan *IncomingMessage
 	wg       *sync.WaitGroup
+
+	// inflight counts the messages (and copies of them) being handled, draining
+	// is set once shutdown starts and drained is closed when inflight reaches 0
+	inflightMu sync.Mutex
+	inflight   int
+	draining   bool
+	drained    chan struct{}
+	stats      statsCounter
 }
 
 type ClientOptions struct {
 	DisableSecurity bool
 	RelayDebugUrl   string
 	Logging         bool
+	// Routes forward messages to local urls other than the default localURL
+	Routes []*Route
+	// Recorder stores every relayed request and its local response
+	Recorder *Recorder
+	// VerifyKey verifies the signature of every relayed request
+	VerifyKey *signature.Key
+	// RejectUnverified responds with a 401 instead of forwarding requests failing verification
+	RejectUnverified bool
+	// OnConnect is called with the public url of the relay whenever a connection is established
+	OnConnect func(receiveURL string)
+	// OnRecord is called with every handled request and its local response
+	OnRecord func(rec *Record)
+	// Transformations rewrite requests before they're forwarded and responses before they're returned
+	Transformations *Transformations
+	// LocalTimeout is how long to wait for the local server to respond
+	LocalTimeout time.Duration
+	// DrainTimeout is how long Listen waits for in-flight requests once its context is canceled
+	DrainTimeout time.Duration
+	// MaxConcurrency limits how many requests are forwarded at once (0 is unlimited)
+	MaxConcurrency int
+	// Serial forwards requests one at a time, in the order they were received
+	Serial bool
+	// RateLimit limits how many requests are started per second (0 is unlimited)
+	RateLimit float64
+	// RateBurst is how many requests may be started at once under the rate limit
+	RateBurst int
 }
 
 func NewClient(token string, localURL *url.URL, opts *ClientOptions) *Client {
 	wsProto := "wss"
+	httpProto := "https"
 	apiHost := defaultAPIHost
+	receiveURLTemplate := "https://play.svix.com/in/%s/"
 	logging := false
+	var routes []*Route
+	var recorder *Recorder
+	var verifyKey *signature.Key
+	rejectUnverified := false
+	var onConnect func(string)
+	var onRecord func(*Record)
+	var transformations *Transformations
+	drainTimeout := defaultDrainTimeout
+	localTimeout := defaultTimeout
+	maxConcurrency := 0
+	rateLimit := 0.0
+	rateBurst := 1
 	if opts != nil {
 		if opts.DisableSecurity {
 			wsProto = "ws"
+			httpProto = "http"
 		}
 		if opts.RelayDebugUrl != "" {
 			apiHost = opts.RelayDebugUrl
+			// a custom relay (e.g. `svix relay serve`) receives requests itself
+			receiveURLTemplate = fmt.Sprintf("%s://%s/in/%%s/", httpProto, apiHost)
 		}
 		if opts.Logging {
 			logging = opts.Logging
 			token = fmt.Sprintf("c_%s", token)
 		}
+		routes = append(routes, opts.Routes...)
+		recorder = opts.Recorder
+		verifyKey = opts.VerifyKey
+		rejectUnverified = opts.RejectUnverified
+		onConnect = opts.OnConnect
+		onRecord = opts.OnRecord
+		transformations = opts.Transformations
+		if opts.LocalTimeout > 0 {
+			localTimeout = opts.LocalTimeout
+		}
+		if opts.DrainTimeout > 0 {
+			drainTimeout = opts.DrainTimeout
+		}
+		maxConcurrency = opts.MaxConcurrency
+		if opts.Serial {
+			maxConcurrency = 1
+		}
+		rateLimit = opts.RateLimit
+		if opts.RateBurst > 0 {
+			rateBurst = opts.RateBurst
+		}
+	}
+	if localURL != nil {
+		routes = append(routes, NewDefaultRoute(localURL))
 	}
 
-	return &Client{
+	c := &Client{
 		token:              token,
 		logging:            logging,
 		websocketURL:       fmt.Sprintf("%s://%s/%s/listen/", wsProto, apiHost, apiPrefix),
-		localURL:           localURL,
-		receiveURLTemplate: "https://play.svix.com/in/%s/",
+		routes:             routes,
+		recorder:           recorder,
+		verifyKey:          verifyKey,
+		rejectUnverified:   rejectUnverified,
+		onConnect:          onConnect,
+		onRecord:           onRecord,
+		transformations:    transformations,
+		drainTimeout:       drainTimeout,
+		receiveURLTemplate: receiveURLTemplate,
 		dialer: &websocket.Dialer{
 			HandshakeTimeout: 10 * time.Second,
 			Proxy:            http.ProxyFromEnvironment,
 		},
-		httpClient: &http.Client{
-			CheckRedirect: func(req *http.Request, via []*http.Request) error {
-				return http.ErrUseLastResponse
-			},
-			Timeout: defaultTimeout,
-		},
+		httpClient: NewLocalHTTPClient(localTimeout),
 		stopRead:          make(chan struct{}, 10),
 		stopWrite:         make(chan struct{}, 10),
 
@@ -95,13 +189,31 @@ func NewClient(token string, localURL *url.URL, opts *ClientOptions) *Client {
 		sendChan: make(chan *OutgoingMessageEvent, 10),
 		recChan:  make(chan *IncomingMessage, 10),
 	}
+	if maxConcurrency > 0 || rateLimit > 0 {
+		c.dispatcher = newDispatcher(maxConcurrency, rateLimit, rateBurst, c.handle)
+	}
+	return c
+}
+
+// NewLocalHTTPClient returns the http client requests are forwarded to the local
+// server with. Redirects are not followed, they are passed back as they are.
+func NewLocalHTTPClient(timeout time.Duration) *http.Client {
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+		Timeout: timeout,
+	}
 }
 
 type Stop = struct {}
 
 func (c *Client) Listen(ctx context.Context) {
 	if c.conn != nil {
-		fmt.Printf("relay already listening\n")
+		fmt.Fprintf(color.Output, "relay already listening\n")
 		return
 	}
 
@@ -114,6 +226,12 @@ func (c *Client) Listen(ctx context.Context) {
 	}
 	reconnectAttempts := 0
 
+	if c.dispatcher != nil {
+		stopDispatcher := make(chan struct{})
+		defer close(stopDispatcher)
+		go c.dispatcher.run(stopDispatcher)
+	}
+
 	for {
 		err := c.connect(ctx)
 		if errors.Is(err, context.Canceled) {
@@ -129,7 +247,11 @@ func (c *Client) Listen(ctx context.Context) {
 				backoff = reconnectBackoffSteps[reconnectAttempts]
 			}
 			color.Yellow("Reattempting connection in %v\n", backoff)
-			time.Sleep(backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
 
 			reconnectAttempts += 1
 			continue
@@ -140,8 +262,7 @@ func (c *Client) Listen(ctx context.Context) {
 
 		select {
 		case <-ctx.Done():
-			c.stopRead <- Stop{}
-			c.stopWrite <- Stop{}
+			c.shutdown()
 			return
 		case <-c.errChan:
 			c.stopRead <- Stop{}
@@ -151,6 +272,55 @@ func (c *Client) Listen(ctx context.Context) {
 		}
     }
 }
+// shutdown stops accepting messages, waits for in-flight messages to be
+// handled (up to the drain timeout) and closes the connection.
+func (c *Client) shutdown() {
+	c.inflightMu.Lock()
+	c.draining = true
+	drained := make(chan struct{})
+	if c.inflight == 0 {
+		close(drained)
+	} else {
+		c.drained = drained
+	}
+	c.inflightMu.Unlock()
+	select {
+	case <-drained:
+	case <-time.After(c.drainTimeout):
+		color.Yellow("Timed out waiting for in-flight requests after %v\n", c.drainTimeout)
+	}
+
+	// the close message is sent after any queued responses, the read
+	// loop then exits once the relay acknowledges it. The loops may have
+	// already stopped on a connection error, so give up on them after
+	// closeTimeout rather than blocking on a full channel.
+	closeCtx, cancel := context.WithTimeout(context.Background(), closeTimeout)
+	defer cancel()
+	select {
+	case c.stopRead <- Stop{}:
+	case <-closeCtx.Done():
+	}
+	select {
+	case c.sendChan <- nil:
+	case <-closeCtx.Done():
+	}
+	closed := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-closeCtx.Done():
+	}
+	c.close()
+}
+
+// Stats returns a summary of the requests handled so far.
+func (c *Client) Stats() Stats {
+	return c.stats.get()
+}
+
 func (c *Client) close() {
 	if c.conn != nil {
 		c.conn.Close()
@@ -201,15 +371,24 @@ func (c *Client) connect(ctx context.Context) error {
 		return err
 	}
 	url := fmt.Sprintf(c.receiveURLTemplate, startMsgIn.Data.Token)
-	fmt.Printf(`Webhook relay is now listening at
+	if c.onConnect != nil {
+		c.onConnect(url)
+	}
+	fmt.Fprintf(color.Output, `Webhook relay is now listening at
 %s
 
-All requests on this endpoint will be forwarded to your local URL:
-%s
-`, pretty.MakeTerminalLink(url, url), c.localURL)
+`, pretty.MakeTerminalLink(url, url))
+	if len(c.routes) == 1 && c.routes[0].IsDefault() && len(c.routes[0].Targets) == 1 {
+		fmt.Fprintf(color.Output, "All requests on this endpoint will be forwarded to your local URL:\n%s\n", c.routes[0].Targets[0])
+	} else {
+		fmt.Fprintln(color.Output, "All requests on this endpoint will be routed to your local URLs:")
+		for _, route := range c.routes {
+			fmt.Fprintf(color.Output, "  %s\n", route)
+		}
+	}
 	if c.logging {
 		viewUrl := fmt.Sprintf("https://play.svix.com/view/%s/", c.token)
-		fmt.Printf(`
+		fmt.Fprintf(color.Output, `
 View logs and debug information at
 %s
 To disable logging run "svix listen --no-logging"
@@ -245,7 +424,12 @@ func (c *Client) recLoop() {
 			c.sendErrorMaybe(err, c.stopRead)
 			return
 		}
-		go c.handleIncomingMessage(packet)
+		c.addInflight()
+		if c.dispatcher != nil {
+			c.dispatcher.enqueue(packet)
+		} else {
+			go c.handle(packet)
+		}
 	}
 }
 
@@ -259,7 +443,8 @@ func (c *Client) sendLoop() {
 		select {
 		case msg, ok := <-c.sendChan:
 			_ = c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
+			// a nil message is queued by shutdown
+			if !ok || msg == nil {
 				_ = c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
 				return
 			}
@@ -287,6 +472,33 @@ func (c *Client) sendLoop() {
 	}
 }
 
+func (c *Client) handle(packet []byte) {
+	defer c.doneInflight()
+	c.handleIncomingMessage(packet)
+}
+
+func (c *Client) addInflight() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+	c.inflight++
+}
+
+func (c *Client) doneInflight() {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+	c.inflight--
+	if c.inflight == 0 && c.drained != nil {
+		close(c.drained)
+		c.drained = nil
+	}
+}
+
+func (c *Client) isDraining() bool {
+	c.inflightMu.Lock()
+	defer c.inflightMu.Unlock()
+	return c.draining
+}
+
 func (c *Client) handleIncomingMessage(packet []byte) {
 	var msg IncomingMessage
 	if err := json.Unmarshal(packet, &msg); err != nil {
@@ -294,25 +506,111 @@ func (c *Client) handleIncomingMessage(packet []byte) {
 	}
 	switch msg.Type {
 	case MessageTypeEvent:
+		receivedAt := time.Now()
 		var msgData IncomingMessageEventData
 		err := json.Unmarshal(msg.Data, &msgData)
 		if err != nil {
 			color.Red("Received Invalid Webhook message... skipping\n")
 			return
 		}
-		color.Blue("<- Forwarding Message to: %s", c.localURL.String())
-		res, err := c.makeLocalRequest(c.localURL, msgData)
+		body, err := base64.StdEncoding.DecodeString(msgData.Body)
+		if err != nil {
+			color.Red("Received Invalid Webhook message... skipping\n")
+			return
+		}
+		rec := newRecord(&msgData, body, receivedAt)
+		if c.isDraining() {
+			color.Yellow("<- Received message while shutting down")
+			c.fail(rec, http.StatusServiceUnavailable, "svix listen is shutting down")
+			return
+		}
+		if c.verifyKey != nil {
+			verifyErr := c.verify(&msgData, body)
+			verified := verifyErr == nil
+			rec.Verified = &verified
+			if verifyErr != nil && c.rejectUnverified {
+				out := c.sendError(msgData.ID, http.StatusUnauthorized, verifyErr.Error())
+				rec.setResponse(out, time.Since(receivedAt))
+				c.stats.rejected()
+				c.record(rec)
+				return
+			}
+		}
+		route := matchRoute(c.routes, &msgData, body)
+		if route == nil {
+			color.Yellow("<- No route matched message")
+			c.fail(rec, http.StatusNotFound, "no route matched")
+			return
+		}
+		fwdData, targetPath, err := c.transformRequest(&msgData, body)
+		if err != nil {
+			color.Red("Failed to transform request: \n%s\n", err.Error())
+			c.fail(rec, http.StatusBadGateway, fmt.Sprintf("failed to transform request: %s", err.Error()))
+			return
+		}
+		res, err := c.forward(route, fwdData, targetPath)
 		if err != nil {
 			color.Red("Failed to make request to local server: \n%s\n", err.Error())
+			c.fail(rec, localErrorStatus(err), fmt.Sprintf("failed to make request to local server: %s", err.Error()))
 			return
 		}
 
-		c.processResponse(msgData.ID, res)
+		out := c.processResponse(&msgData, body, res)
+		latency := time.Since(receivedAt)
+		rec.setResponse(out, latency)
+		c.stats.forwarded(latency)
+		c.record(rec)
 	default:
 		return
 	}
 }
 
+// transformRequest applies the request transformations, returning the message
+// to forward and the path replacing the path of the local url (if any).
+func (c *Client) transformRequest(msg *IncomingMessageEventData, body []byte) (IncomingMessageEventData, string, error) {
+	if c.transformations == nil || len(c.transformations.Request) == 0 {
+		return *msg, "", nil
+	}
+	out, targetPath, applied, err := c.transformations.transformRequest(msg, body)
+	if err != nil {
+		return out, "", err
+	}
+	if applied > 0 {
+		color.Cyan("   Applied %d request transformation(s)", applied)
+	}
+	return out, targetPath, nil
+}
+
+// forward sends the message to every target of the route, only the response
+// of the first target is returned and forwarded to the webhook sender.
+func (c *Client) forward(route *Route, msg IncomingMessageEventData, targetPath string) (*http.Response, error) {
+	for _, target := range route.Targets[1:] {
+		target = forwardURL(target, msg.Path, targetPath)
+		c.addInflight()
+		go func(target *url.URL) {
+			defer c.doneInflight()
+			// copies count towards --max-concurrency like the messages themselves
+			if c.dispatcher != nil {
+				c.dispatcher.acquire()
+				defer c.dispatcher.release()
+			}
+			color.Blue("<- Forwarding Message copy to: %s", target.String())
+			res, err := c.makeLocalRequest(target, msg)
+			if err != nil {
+				color.Red("Failed to make request to %s: \n%s\n", target.String(), err.Error())
+				return
+			}
+			defer res.Body.Close()
+			_, _ = io.Copy(io.Discard, res.Body)
+			color.Green("-> Received \"%s\" response from %s (not forwarded)\n", res.Status, target.String())
+		}(target)
+	}
+
+	target := forwardURL(route.Targets[0], msg.Path, targetPath)
+	color.Blue("<- Forwarding Message to: %s", target.String())
+	return c.makeLocalRequest(target, msg)
+}
+
 func formatRespHeaders(h http.Header) map[string]string {
 	if h.Get("User-Agent") == "Go-http-client/1.1" {
 		h.Set("User-Agent", "")
@@ -330,14 +628,20 @@ func (c *Client) makeLocalRequest(url *url.URL, msg IncomingMessageEventData) (*
 		return nil, err
 	}
 
+	return c.httpClient.Do(newLocalRequest(url, msg.Method, msg.Headers, body))
+}
+
+func newLocalRequest(url *url.URL, method string, headers map[string]string, body []byte) *http.Request {
 	req := &http.Request{
-		Method: msg.Method,
+		Method: method,
 		Header: http.Header{},
 		URL:    url,
 		Body:   io.NopCloser(bytes.NewReader(body)),
+		// avoid chunked encoding, the size of the body is known
+		ContentLength: int64(len(body)),
 	}
 
-	for name, value := range msg.Headers {
+	for name, value := range headers {
 		if strings.ToLower(name) == "host" {
 			// go requires the host to be set
 			// explicitly otherwise it fails with
@@ -347,25 +651,131 @@ func (c *Client) makeLocalRequest(url *url.URL, msg IncomingMessageEventData) (*
 			req.Header.Add(name, value)
 		}
 	}
-	return http.DefaultClient.Do(req)
+	return req
 }
 
-func (c *Client) processResponse(id string, res *http.Response) {
+// verify checks the signature of the message, annotating the output with the result.
+func (c *Client) verify(msg *IncomingMessageEventData, body []byte) error {
+	headers := http.Header{}
+	for name, value := range msg.Headers {
+		headers.Set(name, value)
+	}
+
+	// the same svix-* or webhook-* headers as svix verify are accepted
+	msgID, rawTimestamp, sig := capture.SignatureHeaders(headers)
+	if msgID == "" || rawTimestamp == "" || sig == "" {
+		color.Red("   Signature verification failed: missing signature headers")
+		return fmt.Errorf("signature verification failed: missing signature headers")
+	}
+	timestamp, err := signature.ParseTimestamp(rawTimestamp)
+	if err == nil {
+		err = c.verifyKey.Verify(msgID, timestamp, body, sig)
+	}
+	if err != nil {
+		color.Red("   Signature verification failed: %s", err.Error())
+		return fmt.Errorf("signature verification failed: %s", err.Error())
+	}
+	if err := signature.CheckTimestamp(timestamp, time.Now(), signature.DefaultTolerance); err != nil {
+		color.Red("   Signature is valid but failed timestamp verification: %s", err.Error())
+		return fmt.Errorf("signature is valid but failed timestamp verification: %s", err.Error())
+	}
+	color.Green("   Signature is valid")
+	return nil
+}
+
+func (c *Client) processResponse(msg *IncomingMessageEventData, reqBody []byte, res *http.Response) *OutgoingMessageEventData {
 	buf, _ := io.ReadAll(res.Body)
 	defer res.Body.Close()
 
+	color.Green("-> Received \"%s\" response, forwarding to webhook sender\n", res.Status)
+	out := &OutgoingMessageEventData{
+		ID:      msg.ID,
+		Status:  res.StatusCode,
+		Headers: formatRespHeaders(res.Header),
+		Body:    base64.StdEncoding.EncodeToString(buf),
+	}
+	if c.transformations != nil && len(c.transformations.Response) > 0 {
+		applied, err := c.transformations.transformResponse(msg, reqBody, out, buf)
+		if err != nil {
+			color.Red("Failed to transform response, forwarding it unchanged: \n%s\n", err.Error())
+		} else if applied > 0 {
+			color.Cyan("   Applied %d response transformation(s)", applied)
+		}
+	}
+	return c.send(out)
+}
+
+// sendError responds to the webhook sender on behalf of the local server.
+func (c *Client) sendError(id string, status int, reason string) *OutgoingMessageEventData {
+	color.Red("-> Responding with \"%d %s\" to webhook sender\n", status, http.StatusText(status))
+	return c.sendResponse(id, status, map[string]string{
+		"Content-Type":     "text/plain; charset=utf-8",
+		CLIGeneratedHeader: "true",
+	}, []byte(fmt.Sprintf("svix listen: %s\n", reason)))
+}
+
+// fail responds with an error to a message that couldn't be forwarded, and records it.
+func (c *Client) fail(rec *Record, status int, reason string) {
+	out := c.sendError(rec.ID, status, reason)
+	rec.Error = reason
+	rec.setResponse(out, time.Since(rec.Timestamp))
+	c.stats.failed()
+	c.record(rec)
+}
+
+// localErrorStatus returns the status reported to the webhook sender when the local request failed.
+func localErrorStatus(err error) int {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+func (c *Client) sendResponse(id string, status int, headers map[string]string, body []byte) *OutgoingMessageEventData {
+	return c.send(&OutgoingMessageEventData{
+		ID:      id,
+		Status:  status,
+		Headers: headers,
+		Body:    base64.StdEncoding.EncodeToString(body),
+	})
+}
+
+func (c *Client) send(data *OutgoingMessageEventData) *OutgoingMessageEventData {
 	msg := &OutgoingMessageEvent{
 		Type:    MessageTypeEvent,
 		Version: version,
-		Data: OutgoingMessageEventData{
-			ID:      id,
-			Status:  res.StatusCode,
-			Headers: formatRespHeaders(res.Header),
-			Body:    base64.StdEncoding.EncodeToString(buf),
-		},
+		Data:    *data,
 	}
-	color.Green("-> Received \"%s\" response, forwarding to webhook sender\n", res.Status)
 	c.SendMessage(msg)
+	return &msg.Data
+}
+
+// Replay sends a request again, routing it like a newly relayed request.
+// The response isn't forwarded to the webhook sender.
+func (c *Client) Replay(rec *Record) (*http.Response, error) {
+	msg := rec.message()
+	route := matchRoute(c.routes, &msg, rec.Body)
+	if route == nil {
+		return nil, fmt.Errorf("no route matched")
+	}
+	fwdMsg, targetPath, err := c.transformRequest(&msg, rec.Body)
+	if err != nil {
+		return nil, err
+	}
+	return c.forward(route, fwdMsg, targetPath)
+}
+
+func (c *Client) record(rec *Record) {
+	if c.onRecord != nil {
+		c.onRecord(rec)
+	}
+	if c.recorder == nil {
+		return
+	}
+	if err := c.recorder.Write(rec); err != nil {
+		color.Red("Failed to record request %s: \n%s\n", rec.ID, err.Error())
+	}
 }
 
 func (c *Client) sendErrorMaybe(err error, stopChan chan(struct{})) {
@@ -0,0 +1,58 @@
+package relay
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShutdownWithStoppedLoops(t *testing.T) {
+	// unbuffered channels nobody reads from, as if the read and send
+	// loops had already exited on a connection error
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	c := &Client{
+		stopRead:     make(chan struct{}),
+		sendChan:     make(chan *OutgoingMessageEvent),
+		wg:           wg,
+		drainTimeout: 10 * time.Millisecond,
+	}
+
+	done := make(chan struct{})
+	go func() {
+		c.shutdown()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeTimeout + time.Second):
+		t.Fatal("shutdown blocked on the stopped loops")
+	}
+}
+
+func TestShutdownWaitsForInflight(t *testing.T) {
+	wg := &sync.WaitGroup{}
+	c := &Client{
+		stopRead:     make(chan struct{}, 1),
+		sendChan:     make(chan *OutgoingMessageEvent, 1),
+		wg:           wg,
+		drainTimeout: 5 * time.Second,
+	}
+	c.addInflight()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		c.doneInflight()
+	}()
+
+	start := time.Now()
+	c.shutdown()
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("shutdown took %v, want it to return once the request is done", elapsed)
+	}
+	if !c.isDraining() {
+		t.Error("client isn't draining after shutdown")
+	}
+	if msg := <-c.sendChan; msg != nil {
+		t.Errorf("queued %+v, want the nil close message", msg)
+	}
+}
@@ -0,0 +1,167 @@
+package relay
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+)
+
+const defaultRouteMatch = "default"
+
+// Route forwards the messages it matches to one or more local targets.
+//
+// Routes are written as MATCH=URL[,URL...] where MATCH is one of:
+//
+//	default             matches messages no other route matched
+//	path:GLOB           matches the path requested after the relay token, which
+//	                    is only relayed by "svix relay serve"
+//	header:NAME:GLOB    matches the value of the request header NAME
+//	GLOB                matches the event type (the "type" field of the json body)
+type Route struct {
+	Match   string
+	Targets []*url.URL
+
+	matcher *matcher
+}
+
+// matcher matches messages by event type, path or header, see Route for the syntax.
+type matcher struct {
+	kind  string
+	key   string
+	value string
+}
+
+func ParseRoute(s string) (*Route, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid route %q, expected MATCH=URL[,URL...]", s)
+	}
+
+	m, err := parseMatcher(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid route %q: %s", s, err)
+	}
+	route := &Route{
+		Match:   parts[0],
+		matcher: m,
+	}
+
+	for _, rawTarget := range strings.Split(parts[1], ",") {
+		target, err := url.Parse(strings.TrimSpace(rawTarget))
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return nil, fmt.Errorf("invalid target url %q in route %q", rawTarget, s)
+		}
+		route.Targets = append(route.Targets, target)
+	}
+	return route, nil
+}
+
+// NewDefaultRoute creates a route forwarding all otherwise unmatched messages to target.
+func NewDefaultRoute(target *url.URL) *Route {
+	return &Route{
+		Match:   defaultRouteMatch,
+		Targets: []*url.URL{target},
+		matcher: &matcher{kind: defaultRouteMatch},
+	}
+}
+
+func (r *Route) IsDefault() bool {
+	return r.matcher.kind == defaultRouteMatch
+}
+
+// IsPath reports whether the route matches messages by path.
+func (r *Route) IsPath() bool {
+	return r.matcher.kind == "path"
+}
+
+func (r *Route) String() string {
+	targets := make([]string, len(r.Targets))
+	for i, target := range r.Targets {
+		targets[i] = target.String()
+	}
+	return fmt.Sprintf("%s -> %s", r.Match, strings.Join(targets, ", "))
+}
+
+func parseMatcher(s string) (*matcher, error) {
+	m := &matcher{}
+	switch {
+	case s == defaultRouteMatch:
+		m.kind = defaultRouteMatch
+	case strings.HasPrefix(s, "path:"):
+		m.kind = "path"
+		m.value = strings.TrimPrefix(s, "path:")
+	case strings.HasPrefix(s, "header:"):
+		header := strings.SplitN(strings.TrimPrefix(s, "header:"), ":", 2)
+		if len(header) != 2 || header[0] == "" {
+			return nil, fmt.Errorf("invalid header match %q, expected header:NAME:GLOB", s)
+		}
+		m.kind = "header"
+		m.key = header[0]
+		m.value = header[1]
+	default:
+		m.kind = "event-type"
+		m.value = s
+	}
+	if _, err := path.Match(m.value, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %s", m.value, err)
+	}
+	return m, nil
+}
+
+func (m *matcher) matches(msg *IncomingMessageEventData, body []byte) bool {
+	switch m.kind {
+	case defaultRouteMatch:
+		return true
+	case "path":
+		ok, _ := path.Match(m.value, msg.Path)
+		return ok
+	case "header":
+		for name, value := range msg.Headers {
+			if strings.EqualFold(name, m.key) {
+				ok, _ := path.Match(m.value, value)
+				return ok
+			}
+		}
+		return false
+	default:
+		eventType := eventTypeFromBody(body)
+		if eventType == "" {
+			return false
+		}
+		ok, _ := path.Match(m.value, eventType)
+		return ok
+	}
+}
+
+// matchRoute returns the first matching route, falling back to the default route.
+func matchRoute(routes []*Route, msg *IncomingMessageEventData, body []byte) *Route {
+	var fallback *Route
+	for _, route := range routes {
+		if route.IsDefault() {
+			if fallback == nil {
+				fallback = route
+			}
+			continue
+		}
+		if route.matcher.matches(msg, body) {
+			return route
+		}
+	}
+	return fallback
+}
+
+func eventTypeFromBody(body []byte) string {
+	var payload struct {
+		Type      string `json:"type"`
+		EventType string `json:"eventType"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	if payload.Type != "" {
+		return payload.Type
+	}
+	return payload.EventType
+}
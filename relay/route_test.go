@@ -0,0 +1,127 @@
+package relay
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestParseRoute(t *testing.T) {
+	tests := []struct {
+		route   string
+		match   string
+		targets []string
+		wantErr bool
+	}{
+		{route: "default=http://localhost:8000/", match: "default", targets: []string{"http://localhost:8000/"}},
+		{route: "invoice.*=http://localhost:8001/a, http://localhost:8002/b", match: "invoice.*", targets: []string{"http://localhost:8001/a", "http://localhost:8002/b"}},
+		{route: "header:X-Tenant:acme-*=http://localhost:8000/", match: "header:X-Tenant:acme-*", targets: []string{"http://localhost:8000/"}},
+		{route: "path:/billing/*=http://localhost:8000/", match: "path:/billing/*", targets: []string{"http://localhost:8000/"}},
+		{route: "http://localhost:8000/", wantErr: true},
+		{route: "=http://localhost:8000/", wantErr: true},
+		{route: "invoice.*=", wantErr: true},
+		{route: "invoice.*=localhost:8000", wantErr: true},
+		{route: "header:=http://localhost:8000/", wantErr: true},
+		{route: "[=http://localhost:8000/", wantErr: true},
+	}
+	for _, tt := range tests {
+		route, err := ParseRoute(tt.route)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRoute(%q) succeeded, want an error", tt.route)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseRoute(%q) failed: %s", tt.route, err)
+			continue
+		}
+		if route.Match != tt.match {
+			t.Errorf("ParseRoute(%q).Match = %q, want %q", tt.route, route.Match, tt.match)
+		}
+		if len(route.Targets) != len(tt.targets) {
+			t.Errorf("ParseRoute(%q) has %d targets, want %d", tt.route, len(route.Targets), len(tt.targets))
+			continue
+		}
+		for i, target := range route.Targets {
+			if target.String() != tt.targets[i] {
+				t.Errorf("ParseRoute(%q).Targets[%d] = %s, want %s", tt.route, i, target, tt.targets[i])
+			}
+		}
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	var routes []*Route
+	for _, raw := range []string{
+		"path:/billing/*=http://localhost:8001/",
+		"header:X-Tenant:acme-*=http://localhost:8002/",
+		"invoice.*=http://localhost:8003/",
+		"default=http://localhost:8000/",
+		"user.created=http://localhost:8004/",
+	} {
+		route, err := ParseRoute(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		routes = append(routes, route)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		body    string
+		want    string
+	}{
+		{name: "event type glob", body: `{"type":"invoice.paid"}`, want: "invoice.*"},
+		{name: "eventType field", body: `{"eventType":"invoice.paid"}`, want: "invoice.*"},
+		{name: "exact event type after default", body: `{"type":"user.created"}`, want: "user.created"},
+		{name: "header glob", headers: map[string]string{"x-tenant": "acme-eu"}, body: `{"type":"invoice.paid"}`, want: "header:X-Tenant:acme-*"},
+		{name: "header not matching", headers: map[string]string{"X-Tenant": "other"}, body: `{"type":"invoice.paid"}`, want: "invoice.*"},
+		{name: "path glob", path: "/billing/stripe", body: `{"type":"invoice.paid"}`, want: "path:/billing/*"},
+		{name: "glob doesn't cross slashes", path: "/billing/stripe/eu", body: `{"type":"other"}`, want: "default"},
+		{name: "non json body", body: `invoice.paid`, want: "default"},
+		{name: "no match", body: `{"type":"user.deleted"}`, want: "default"},
+	}
+	for _, tt := range tests {
+		msg := &IncomingMessageEventData{Path: tt.path, Headers: tt.headers}
+		route := matchRoute(routes, msg, []byte(tt.body))
+		if route == nil {
+			t.Errorf("%s: no route matched, want %s", tt.name, tt.want)
+			continue
+		}
+		if route.Match != tt.want {
+			t.Errorf("%s: matched %s, want %s", tt.name, route.Match, tt.want)
+		}
+	}
+
+	if route := matchRoute(routes[:3], &IncomingMessageEventData{}, []byte(`{}`)); route != nil {
+		t.Errorf("matched %s without a default route, want no match", route.Match)
+	}
+}
+
+func TestForwardURL(t *testing.T) {
+	tests := []struct {
+		target      string
+		requestPath string
+		targetPath  string
+		want        string
+	}{
+		{target: "http://localhost:8000/webhook/", want: "http://localhost:8000/webhook/"},
+		{target: "http://localhost:8000/webhook/", requestPath: "/", want: "http://localhost:8000/webhook/"},
+		{target: "http://localhost:8000/webhook/", requestPath: "/billing/stripe", want: "http://localhost:8000/webhook/billing/stripe"},
+		{target: "http://localhost:8000/webhook", requestPath: "/billing", want: "http://localhost:8000/webhook/billing"},
+		{target: "http://localhost:8000", requestPath: "/billing", want: "http://localhost:8000/billing"},
+		{target: "http://localhost:8000/webhook/", requestPath: "/billing", targetPath: "/other", want: "http://localhost:8000/other"},
+	}
+	for _, tt := range tests {
+		target, err := url.Parse(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := forwardURL(target, tt.requestPath, tt.targetPath).String()
+		if got != tt.want {
+			t.Errorf("forwardURL(%s, %q, %q) = %s, want %s", tt.target, tt.requestPath, tt.targetPath, got, tt.want)
+		}
+	}
+}
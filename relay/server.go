@@ -0,0 +1,355 @@
+package relay
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	DefaultServerAddr     = "localhost:8090"
+	defaultMaxBodySize    = 10 << 20 // 10MiB
+	defaultServerTimeout  = defaultTimeout
+	serverShutdownTimeout = 5 * time.Second
+)
+
+// Server is a minimal implementation of the server side of the relay protocol.
+// It accepts listener connections on /api/v1/listen/ and forwards any request
+// made to /in/<token>/ to the listener registered with that token.
+type Server struct {
+	addr            string
+	responseTimeout time.Duration
+	upgrader        websocket.Upgrader
+
+	mu        sync.Mutex
+	listeners map[string]*serverConn
+}
+
+type ServerOptions struct {
+	// ResponseTimeout is how long an ingress request waits for the listener to respond
+	ResponseTimeout time.Duration
+}
+
+type serverConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	done    chan struct{}
+
+	pendingMu sync.Mutex
+	pending   map[string]chan *OutgoingMessageEventData
+}
+
+func NewServer(addr string, opts *ServerOptions) *Server {
+	responseTimeout := defaultServerTimeout
+	if opts != nil {
+		if opts.ResponseTimeout > 0 {
+			responseTimeout = opts.ResponseTimeout
+		}
+	}
+
+	return &Server{
+		addr:            addr,
+		responseTimeout: responseTimeout,
+		upgrader: websocket.Upgrader{
+			HandshakeTimeout: 10 * time.Second,
+		},
+		listeners: map[string]*serverConn{},
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(fmt.Sprintf("/%s/listen/", apiPrefix), s.handleListen)
+	mux.HandleFunc("/in/", s.handleIngress)
+	return mux
+}
+
+// ListenAndServe serves the relay until the context is canceled.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:    s.addr,
+		Handler: s.Handler(),
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.ListenAndServe()
+	}()
+
+	fmt.Printf(`Webhook relay server is now running at
+http://%s/
+
+Point "svix listen" at it by running:
+svix listen --local http://localhost:8000/webhook/
+`, s.addr)
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serverShutdownTimeout)
+		defer cancel()
+		s.closeListeners()
+		err := srv.Shutdown(shutdownCtx)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
+
+func (s *Server) handleListen(w http.ResponseWriter, r *http.Request) {
+	conn, err := s.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already replied with an http error
+		return
+	}
+	defer conn.Close()
+
+	_ = conn.SetReadDeadline(time.Now().Add(pongWait))
+	var startMsg OutgoingMessageStart
+	if err := conn.ReadJSON(&startMsg); err != nil {
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	token := startMsg.Data.Token
+	if startMsg.Type != MessageTypeStart || token == "" {
+		closeWithPolicyViolation(conn, "expected start message")
+		return
+	}
+
+	sc := &serverConn{
+		conn:    conn,
+		done:    make(chan struct{}),
+		pending: map[string]chan *OutgoingMessageEventData{},
+	}
+	if !s.register(token, sc) {
+		closeWithPolicyViolation(conn, "token already listening")
+		return
+	}
+	defer s.unregister(token, sc)
+	defer close(sc.done)
+
+	err = sc.writeJSON(&IncomingMessageStart{
+		Type:    MessageTypeStart,
+		Version: version,
+		Data: IncomingMessageStartData{
+			Token: token,
+		},
+	})
+	if err != nil {
+		return
+	}
+	color.Green("-> Listener connected with token %s\n", token)
+
+	for {
+		_, packet, err := conn.ReadMessage()
+		if err != nil {
+			color.Yellow("<- Listener with token %s disconnected\n", token)
+			return
+		}
+		var msg IncomingMessage
+		if err := json.Unmarshal(packet, &msg); err != nil {
+			continue
+		}
+		if msg.Type != MessageTypeEvent {
+			continue
+		}
+		var msgData OutgoingMessageEventData
+		if err := json.Unmarshal(msg.Data, &msgData); err != nil {
+			color.Red("Received invalid response from listener... skipping\n")
+			continue
+		}
+		sc.resolve(&msgData)
+	}
+}
+
+func (s *Server) handleIngress(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/in/"), "/", 2)
+	token := pathParts[0]
+	if token == "" {
+		http.Error(w, "missing token", http.StatusNotFound)
+		return
+	}
+
+	s.mu.Lock()
+	sc, ok := s.listeners[token]
+	s.mu.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("no listener connected for token %s", token), http.StatusNotFound)
+		return
+	}
+
+	if r.ContentLength > defaultMaxBodySize {
+		http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, defaultMaxBodySize))
+	if err != nil {
+		// MaxBytesReader fails once the whole limit has been read
+		if int64(len(body)) >= defaultMaxBodySize {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "failed to read request body", http.StatusBadRequest)
+		return
+	}
+
+	id, err := GenerateToken()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	path := "/"
+	if len(pathParts) > 1 {
+		path += pathParts[1]
+	}
+
+	headers := map[string]string{
+		"Host": r.Host,
+	}
+	for name, value := range r.Header {
+		headers[name] = value[0]
+	}
+
+	data, err := json.Marshal(&IncomingMessageEventData{
+		ID:      id,
+		Headers: headers,
+		Body:    base64.StdEncoding.EncodeToString(body),
+		Method:  r.Method,
+		Path:    path,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resChan := sc.expect(id)
+	defer sc.forget(id)
+
+	color.Blue("<- Relaying %s %s to listener\n", r.Method, r.URL.Path)
+	err = sc.writeJSON(&IncomingMessage{
+		Type:    MessageTypeEvent,
+		Version: version,
+		Data:    data,
+	})
+	if err != nil {
+		http.Error(w, "failed to relay request to listener", http.StatusBadGateway)
+		return
+	}
+
+	timer := time.NewTimer(s.responseTimeout)
+	defer timer.Stop()
+
+	select {
+	case res := <-resChan:
+		writeRelayedResponse(w, res)
+		color.Green("-> Listener responded with \"%d %s\"\n", res.Status, http.StatusText(res.Status))
+	case <-sc.done:
+		http.Error(w, "listener disconnected", http.StatusBadGateway)
+	case <-timer.C:
+		http.Error(w, "timed out waiting for listener response", http.StatusGatewayTimeout)
+		color.Red("Timed out waiting for listener response\n")
+	case <-r.Context().Done():
+	}
+}
+
+func writeRelayedResponse(w http.ResponseWriter, res *OutgoingMessageEventData) {
+	body, err := base64.StdEncoding.DecodeString(res.Body)
+	if err != nil {
+		http.Error(w, "listener sent an invalid response body", http.StatusBadGateway)
+		return
+	}
+	for name, value := range res.Headers {
+		if value == "" || strings.EqualFold(name, "Content-Length") {
+			continue
+		}
+		w.Header().Set(name, value)
+	}
+	status := res.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+func (s *Server) register(token string, sc *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.listeners[token]; ok {
+		return false
+	}
+	s.listeners[token] = sc
+	return true
+}
+
+func (s *Server) unregister(token string, sc *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listeners[token] == sc {
+		delete(s.listeners, token)
+	}
+}
+
+func (s *Server) closeListeners() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sc := range s.listeners {
+		_ = sc.conn.WriteControl(
+			websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+			time.Now().Add(writeWait),
+		)
+	}
+}
+
+func closeWithPolicyViolation(conn *websocket.Conn, reason string) {
+	_ = conn.WriteControl(
+		websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.ClosePolicyViolation, reason),
+		time.Now().Add(writeWait),
+	)
+}
+
+func (sc *serverConn) writeJSON(v interface{}) error {
+	sc.writeMu.Lock()
+	defer sc.writeMu.Unlock()
+	_ = sc.conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return sc.conn.WriteJSON(v)
+}
+
+func (sc *serverConn) expect(id string) chan *OutgoingMessageEventData {
+	sc.pendingMu.Lock()
+	defer sc.pendingMu.Unlock()
+	ch := make(chan *OutgoingMessageEventData, 1)
+	sc.pending[id] = ch
+	return ch
+}
+
+func (sc *serverConn) forget(id string) {
+	sc.pendingMu.Lock()
+	defer sc.pendingMu.Unlock()
+	delete(sc.pending, id)
+}
+
+func (sc *serverConn) resolve(res *OutgoingMessageEventData) {
+	sc.pendingMu.Lock()
+	defer sc.pendingMu.Unlock()
+	if ch, ok := sc.pending[res.ID]; ok {
+		ch <- res
+		delete(sc.pending, res.ID)
+	}
+}
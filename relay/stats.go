@@ -0,0 +1,56 @@
+package relay
+
+import (
+	"sync"
+	"time"
+)
+
+// Stats summarizes the requests handled during a listen session.
+type Stats struct {
+	// Forwarded is the number of requests the local server responded to
+	Forwarded int
+	// Failed is the number of requests that couldn't be forwarded or got no response
+	Failed int
+	// Rejected is the number of requests that failed signature verification and weren't forwarded
+	Rejected int
+	// TotalLatency is the sum of the local server's response times
+	TotalLatency time.Duration
+}
+
+// AverageLatency returns the mean response time of the forwarded requests.
+func (s Stats) AverageLatency() time.Duration {
+	if s.Forwarded == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Forwarded)
+}
+
+type statsCounter struct {
+	mu    sync.Mutex
+	stats Stats
+}
+
+func (s *statsCounter) forwarded(latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Forwarded++
+	s.stats.TotalLatency += latency
+}
+
+func (s *statsCounter) failed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Failed++
+}
+
+func (s *statsCounter) rejected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats.Rejected++
+}
+
+func (s *statsCounter) get() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stats
+}
@@ -0,0 +1,256 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Transformations rewrite requests before they're forwarded to the local server,
+// and responses before they're sent back to the webhook sender.
+//
+// Rules are applied in order, a rule without a match applies to every message.
+// Matches use the same syntax as routes and are checked against the original request.
+type Transformations struct {
+	Request  []*RequestRule  `json:"request"`
+	Response []*ResponseRule `json:"response"`
+}
+
+type RequestRule struct {
+	Match         string            `json:"match,omitempty"`
+	Method        string            `json:"method,omitempty"`
+	Path          string            `json:"path,omitempty"`
+	SetHeaders    map[string]string `json:"setHeaders,omitempty"`
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"`
+	BodyRule
+
+	matcher *matcher
+}
+
+type ResponseRule struct {
+	Match         string            `json:"match,omitempty"`
+	Status        int               `json:"status,omitempty"`
+	SetHeaders    map[string]string `json:"setHeaders,omitempty"`
+	RemoveHeaders []string          `json:"removeHeaders,omitempty"`
+	BodyRule
+
+	matcher *matcher
+}
+
+// BodyRule rewrites a body, fields are dotted paths into a json object (e.g. data.id).
+type BodyRule struct {
+	Body             *string                `json:"body,omitempty"`
+	SetBodyFields    map[string]interface{} `json:"setBodyFields,omitempty"`
+	RemoveBodyFields []string               `json:"removeBodyFields,omitempty"`
+	ReplaceBody      []Replacement          `json:"replaceBody,omitempty"`
+}
+
+type Replacement struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// LoadTransformations reads transformation rules from a json file.
+func LoadTransformations(fileName string) (*Transformations, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var t Transformations
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("invalid transformations file %s: %s", fileName, err)
+	}
+
+	for i, rule := range t.Request {
+		if rule.matcher, err = parseRuleMatcher(rule.Match); err != nil {
+			return nil, fmt.Errorf("invalid request rule %d: %s", i+1, err)
+		}
+	}
+	for i, rule := range t.Response {
+		if rule.matcher, err = parseRuleMatcher(rule.Match); err != nil {
+			return nil, fmt.Errorf("invalid response rule %d: %s", i+1, err)
+		}
+	}
+	return &t, nil
+}
+
+func parseRuleMatcher(match string) (*matcher, error) {
+	if match == "" {
+		return &matcher{kind: defaultRouteMatch}, nil
+	}
+	return parseMatcher(match)
+}
+
+// transformRequest returns a rewritten copy of the message, the path replacing
+// the path of the local url (if any) and the number of rules applied.
+func (t *Transformations) transformRequest(msg *IncomingMessageEventData, body []byte) (IncomingMessageEventData, string, int, error) {
+	out := *msg
+	out.Headers = copyHeaders(msg.Headers)
+	newBody := body
+	targetPath := ""
+	applied := 0
+
+	for _, rule := range t.Request {
+		if !rule.matcher.matches(msg, body) {
+			continue
+		}
+		applied++
+		if rule.Method != "" {
+			out.Method = rule.Method
+		}
+		if rule.Path != "" {
+			targetPath = rule.Path
+		}
+		applyHeaderRules(out.Headers, rule.SetHeaders, rule.RemoveHeaders)
+
+		var err error
+		newBody, err = rule.BodyRule.apply(newBody)
+		if err != nil {
+			return out, "", applied, err
+		}
+	}
+
+	out.Body = base64.StdEncoding.EncodeToString(newBody)
+	deleteHeader(out.Headers, "Content-Length")
+	return out, targetPath, applied, nil
+}
+
+// transformResponse rewrites the response to msg before it's sent to the webhook sender,
+// returning the number of rules applied.
+func (t *Transformations) transformResponse(msg *IncomingMessageEventData, reqBody []byte, res *OutgoingMessageEventData, body []byte) (int, error) {
+	status := res.Status
+	headers := copyHeaders(res.Headers)
+	applied := 0
+	for _, rule := range t.Response {
+		if !rule.matcher.matches(msg, reqBody) {
+			continue
+		}
+		applied++
+		if rule.Status != 0 {
+			status = rule.Status
+		}
+		applyHeaderRules(headers, rule.SetHeaders, rule.RemoveHeaders)
+
+		var err error
+		body, err = rule.BodyRule.apply(body)
+		if err != nil {
+			return applied, err
+		}
+	}
+	deleteHeader(headers, "Content-Length")
+
+	res.Status = status
+	res.Headers = headers
+	res.Body = base64.StdEncoding.EncodeToString(body)
+	return applied, nil
+}
+
+func (r *BodyRule) apply(body []byte) ([]byte, error) {
+	if r.Body != nil {
+		body = []byte(*r.Body)
+	}
+	for _, replacement := range r.ReplaceBody {
+		body = bytes.ReplaceAll(body, []byte(replacement.Old), []byte(replacement.New))
+	}
+	if len(r.SetBodyFields) == 0 && len(r.RemoveBodyFields) == 0 {
+		return body, nil
+	}
+
+	var obj map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, fmt.Errorf("can't set or remove body fields, body is not a json object")
+	}
+	for field, value := range r.SetBodyFields {
+		setField(obj, strings.Split(field, "."), value)
+	}
+	for _, field := range r.RemoveBodyFields {
+		removeField(obj, strings.Split(field, "."))
+	}
+	return json.Marshal(obj)
+}
+
+func setField(obj map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		obj[path[0]] = value
+		return
+	}
+	child, ok := obj[path[0]].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{}
+		obj[path[0]] = child
+	}
+	setField(child, path[1:], value)
+}
+
+func removeField(obj map[string]interface{}, path []string) {
+	if len(path) == 1 {
+		delete(obj, path[0])
+		return
+	}
+	if child, ok := obj[path[0]].(map[string]interface{}); ok {
+		removeField(child, path[1:])
+	}
+}
+
+func applyHeaderRules(headers map[string]string, set map[string]string, remove []string) {
+	for _, name := range remove {
+		deleteHeader(headers, name)
+	}
+	for name, value := range set {
+		deleteHeader(headers, name)
+		headers[name] = value
+	}
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string, len(headers))
+	for name, value := range headers {
+		out[name] = value
+	}
+	return out
+}
+
+func deleteHeader(headers map[string]string, name string) {
+	for key := range headers {
+		if strings.EqualFold(key, name) {
+			delete(headers, key)
+		}
+	}
+}
+
+// forwardURL returns the url a message is forwarded to: target with the path
+// set by a transformation, or with the path requested after the relay token
+// appended to it.
+func forwardURL(target *url.URL, requestPath string, targetPath string) *url.URL {
+	if targetPath != "" {
+		return withPath(target, targetPath)
+	}
+	if requestPath == "" || requestPath == "/" {
+		return target
+	}
+	u := *target
+	u.Path = strings.TrimSuffix(target.Path, "/") + "/" + strings.TrimPrefix(requestPath, "/")
+	u.RawPath = ""
+	return &u
+}
+
+// withPath returns a copy of target with its path replaced, if path is set.
+func withPath(target *url.URL, path string) *url.URL {
+	if path == "" {
+		return target
+	}
+	u := *target
+	u.Path = path
+	u.RawPath = ""
+	return &u
+}
@@ -0,0 +1,194 @@
+package relay
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBodyRuleApply(t *testing.T) {
+	replaced := `{"replaced":true}`
+	tests := []struct {
+		name    string
+		rule    BodyRule
+		body    string
+		want    string
+		wantErr bool
+	}{
+		{name: "no rules", body: "not json", want: "not json"},
+		{name: "replace body", rule: BodyRule{Body: &replaced}, body: "anything", want: replaced},
+		{name: "replace text", rule: BodyRule{ReplaceBody: []Replacement{{Old: "live", New: "test"}}}, body: "sk_live_1 sk_live_2", want: "sk_test_1 sk_test_2"},
+		{
+			name: "set nested field",
+			rule: BodyRule{SetBodyFields: map[string]interface{}{"data.id": "obj_1", "type": "invoice.paid"}},
+			body: `{"type":"invoice.created","data":{"id":"obj_0","amount":100}}`,
+			want: `{"data":{"amount":100,"id":"obj_1"},"type":"invoice.paid"}`,
+		},
+		{
+			name: "set field on a missing path",
+			rule: BodyRule{SetBodyFields: map[string]interface{}{"data.customer.id": "cus_1"}},
+			body: `{"type":"invoice.paid"}`,
+			want: `{"data":{"customer":{"id":"cus_1"}},"type":"invoice.paid"}`,
+		},
+		{
+			name: "set field through a non object",
+			rule: BodyRule{SetBodyFields: map[string]interface{}{"data.id": "obj_1"}},
+			body: `{"data":"text"}`,
+			want: `{"data":{"id":"obj_1"}}`,
+		},
+		{
+			name: "remove nested field",
+			rule: BodyRule{RemoveBodyFields: []string{"data.secret"}},
+			body: `{"data":{"id":"obj_1","secret":"s"}}`,
+			want: `{"data":{"id":"obj_1"}}`,
+		},
+		{
+			name: "remove field on a missing path",
+			rule: BodyRule{RemoveBodyFields: []string{"data.customer.id", "missing"}},
+			body: `{"data":{"id":"obj_1"}}`,
+			want: `{"data":{"id":"obj_1"}}`,
+		},
+		{
+			name: "remove field through a non object",
+			rule: BodyRule{RemoveBodyFields: []string{"data.id"}},
+			body: `{"data":[1,2]}`,
+			want: `{"data":[1,2]}`,
+		},
+		{
+			name: "large numbers are kept",
+			rule: BodyRule{RemoveBodyFields: []string{"x"}},
+			body: `{"amount":12345678901234567890}`,
+			want: `{"amount":12345678901234567890}`,
+		},
+		{name: "set field on non json", rule: BodyRule{SetBodyFields: map[string]interface{}{"a": 1}}, body: "a=1&b=2", wantErr: true},
+		{name: "remove field on non json", rule: BodyRule{RemoveBodyFields: []string{"a"}}, body: "a=1&b=2", wantErr: true},
+		{name: "set field on a json array", rule: BodyRule{SetBodyFields: map[string]interface{}{"a": 1}}, body: `[{"a":0}]`, wantErr: true},
+		{name: "set field on json null", rule: BodyRule{SetBodyFields: map[string]interface{}{"a": 1}}, body: `null`, wantErr: true},
+		{name: "set field on an empty body", rule: BodyRule{SetBodyFields: map[string]interface{}{"a": 1}}, body: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.rule.apply([]byte(tt.body))
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %s, want an error", tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s: got %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func loadTestTransformations(t *testing.T, rules string) *Transformations {
+	t.Helper()
+	fileName := filepath.Join(t.TempDir(), "transform.json")
+	if err := os.WriteFile(fileName, []byte(rules), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	transformations, err := LoadTransformations(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transformations
+}
+
+func TestTransformRequest(t *testing.T) {
+	transformations := loadTestTransformations(t, `{
+		"request": [
+			{"removeHeaders": ["x-debug"], "setHeaders": {"X-Env": "test"}},
+			{"match": "invoice.*", "method": "PUT", "path": "/invoices", "setBodyFields": {"data.env": "test"}},
+			{"match": "user.*", "body": "never applied"}
+		]
+	}`)
+	body := []byte(`{"type":"invoice.paid","data":{"id":"in_1"}}`)
+	msg := &IncomingMessageEventData{
+		Method:  "POST",
+		Path:    "/hooks",
+		Headers: map[string]string{"X-Debug": "1", "x-env": "live", "Content-Length": "44"},
+		Body:    base64.StdEncoding.EncodeToString(body),
+	}
+
+	out, targetPath, applied, err := transformations.transformRequest(msg, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 2 {
+		t.Errorf("applied %d rules, want 2", applied)
+	}
+	if out.Method != "PUT" || targetPath != "/invoices" {
+		t.Errorf("method %s, path %q, want PUT /invoices", out.Method, targetPath)
+	}
+	if want := map[string]string{"X-Env": "test"}; !reflect.DeepEqual(out.Headers, want) {
+		t.Errorf("headers = %v, want %v", out.Headers, want)
+	}
+	newBody, _ := base64.StdEncoding.DecodeString(out.Body)
+	if want := `{"data":{"env":"test","id":"in_1"},"type":"invoice.paid"}`; string(newBody) != want {
+		t.Errorf("body = %s, want %s", newBody, want)
+	}
+	if msg.Method != "POST" || len(msg.Headers) != 3 {
+		t.Errorf("original message was modified: %+v", msg)
+	}
+}
+
+func TestTransformRequestNonJSONBody(t *testing.T) {
+	transformations := loadTestTransformations(t, `{"request": [{"setBodyFields": {"a": 1}}]}`)
+	body := []byte("a=1&b=2")
+	msg := &IncomingMessageEventData{Method: "POST", Headers: map[string]string{}}
+	if _, _, _, err := transformations.transformRequest(msg, body); err == nil {
+		t.Error("transformed a form body with setBodyFields, want an error")
+	}
+}
+
+func TestTransformResponse(t *testing.T) {
+	transformations := loadTestTransformations(t, `{
+		"response": [
+			{"status": 200, "removeHeaders": ["Set-Cookie"], "replaceBody": [{"old": "failed", "new": "ok"}]},
+			{"match": "header:X-Tenant:other", "status": 500}
+		]
+	}`)
+	msg := &IncomingMessageEventData{Headers: map[string]string{"X-Tenant": "acme"}}
+	res := &OutgoingMessageEventData{
+		Status:  502,
+		Headers: map[string]string{"set-cookie": "a=b", "Content-Length": "6", "Content-Type": "text/plain"},
+	}
+	applied, err := transformations.transformResponse(msg, nil, res, []byte("failed"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if applied != 1 || res.Status != 200 {
+		t.Errorf("applied %d rules with status %d, want 1 rule and 200", applied, res.Status)
+	}
+	if want := map[string]string{"Content-Type": "text/plain"}; !reflect.DeepEqual(res.Headers, want) {
+		t.Errorf("headers = %v, want %v", res.Headers, want)
+	}
+	if body, _ := base64.StdEncoding.DecodeString(res.Body); string(body) != "ok" {
+		t.Errorf("body = %q, want ok", body)
+	}
+}
+
+func TestLoadTransformationsInvalid(t *testing.T) {
+	for _, rules := range []string{
+		`{"request": [{"setHeader": {"a": "b"}}]}`,
+		`{"request": [{"match": "header:=x"}]}`,
+		`{"response": [{"match": "["}]}`,
+		`not json`,
+	} {
+		fileName := filepath.Join(t.TempDir(), "transform.json")
+		if err := os.WriteFile(fileName, []byte(rules), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadTransformations(fileName); err == nil {
+			t.Errorf("loaded %s, want an error", rules)
+		} else if !strings.Contains(err.Error(), "invalid") {
+			t.Errorf("error = %s, want it to say what's invalid", err)
+		}
+	}
+}
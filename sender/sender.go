@@ -0,0 +1,66 @@
+// Package sender builds webhook requests the way Svix sends them.
+package sender
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/svix/svix-cli/signature"
+)
+
+const (
+	UserAgent = "Svix-Webhooks/1.0 (sender-cli; +https://www.svix.com/http-sender/)"
+	// Timeout is how long Svix waits for an endpoint to respond
+	Timeout = 15 * time.Second
+)
+
+// RetrySchedule is the delay before each retry of a failed delivery, as used by Svix.
+var RetrySchedule = []time.Duration{
+	5 * time.Second,
+	5 * time.Minute,
+	30 * time.Minute,
+	2 * time.Hour,
+	5 * time.Hour,
+	10 * time.Hour,
+	10 * time.Hour,
+}
+
+// CompressedRetrySchedule returns RetrySchedule with every hour shortened to a
+// second (and at least a second per retry), for retrying while testing.
+func CompressedRetrySchedule() []time.Duration {
+	schedule := make([]time.Duration, len(RetrySchedule))
+	for i, d := range RetrySchedule {
+		schedule[i] = d / 3600
+		if schedule[i] < time.Second {
+			schedule[i] = time.Second
+		}
+	}
+	return schedule
+}
+
+// NewRequest builds a POST of payload to url signed with key, with the same
+// headers Svix sends. The signature headers are named with headerPrefix, see
+// signature.SvixHeaderPrefix. headers are added to the request, e.g. an
+// endpoint's custom headers.
+func NewRequest(ctx context.Context, url string, key *signature.Key, headerPrefix string, msgID string, payload []byte, headers map[string]string) (*http.Request, error) {
+	signatureHeaders, err := key.Headers(headerPrefix, msgID, time.Now(), payload)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", UserAgent)
+	for name, value := range signatureHeaders {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
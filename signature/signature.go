@@ -0,0 +1,216 @@
+// Package signature signs and verifies webhooks, with symmetric (whsec_) secrets
+// as used by Svix, and asymmetric ed25519 (whpk_/whsk_) keys from the Standard
+// Webhooks spec.
+package signature
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	SymmetricPrefix = "whsec_"
+	PublicKeyPrefix = "whpk_"
+	SecretKeyPrefix = "whsk_"
+
+	// SvixHeaderPrefix and StandardHeaderPrefix prefix the id, timestamp and signature headers
+	SvixHeaderPrefix     = "svix-"
+	StandardHeaderPrefix = "webhook-"
+
+	// DefaultTolerance is how far a message's timestamp can be from now, as enforced by the svix libraries
+	DefaultTolerance = 5 * time.Minute
+
+	symmetricVersion  = "v1"
+	asymmetricVersion = "v1a"
+)
+
+var (
+	ErrNoMatchingSignature = errors.New("No matching signature found")
+	ErrMessageTooOld       = errors.New("Message timestamp too old")
+	ErrMessageTooNew       = errors.New("Message timestamp too new")
+	ErrInvalidTimestamp    = errors.New("Invalid timestamp")
+)
+
+// Key is a symmetric secret, or an ed25519 public or secret key.
+type Key struct {
+	secret     []byte
+	publicKey  ed25519.PublicKey
+	privateKey ed25519.PrivateKey
+}
+
+// ParseKey parses a whsec_ secret (the prefix is optional), a whpk_ public key or a whsk_ secret key.
+func ParseKey(s string) (*Key, error) {
+	switch {
+	case strings.HasPrefix(s, PublicKeyPrefix):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, PublicKeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		if len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("public keys must be %d bytes, got %d", ed25519.PublicKeySize, len(b))
+		}
+		return &Key{publicKey: ed25519.PublicKey(b)}, nil
+	case strings.HasPrefix(s, SecretKeyPrefix):
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, SecretKeyPrefix))
+		if err != nil {
+			return nil, err
+		}
+		var privateKey ed25519.PrivateKey
+		switch len(b) {
+		case ed25519.SeedSize:
+			privateKey = ed25519.NewKeyFromSeed(b)
+		case ed25519.PrivateKeySize:
+			privateKey = ed25519.PrivateKey(b)
+		default:
+			return nil, fmt.Errorf("secret keys must be %d or %d bytes, got %d", ed25519.SeedSize, ed25519.PrivateKeySize, len(b))
+		}
+		return &Key{
+			publicKey:  privateKey.Public().(ed25519.PublicKey),
+			privateKey: privateKey,
+		}, nil
+	default:
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, SymmetricPrefix))
+		if err != nil {
+			return nil, err
+		}
+		return &Key{secret: b}, nil
+	}
+}
+
+// GenerateKeyPair generates an ed25519 key pair, returning the whpk_ public key and whsk_ secret key.
+func GenerateKeyPair() (publicKey string, secretKey string, err error) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return PublicKeyPrefix + base64.StdEncoding.EncodeToString(public),
+		SecretKeyPrefix + base64.StdEncoding.EncodeToString(private), nil
+}
+
+// GenerateSecret generates a whsec_ symmetric secret.
+func GenerateSecret() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return SymmetricPrefix + base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Asymmetric reports whether the key is an ed25519 key.
+func (k *Key) Asymmetric() bool {
+	return k.publicKey != nil
+}
+
+// CanSign reports whether the key can sign, which public keys can't.
+func (k *Key) CanSign() bool {
+	return !k.Asymmetric() || k.privateKey != nil
+}
+
+// Version is the signature version the key signs and verifies, v1 or v1a.
+func (k *Key) Version() string {
+	if k.Asymmetric() {
+		return asymmetricVersion
+	}
+	return symmetricVersion
+}
+
+// Size is the length of the key in bytes.
+func (k *Key) Size() int {
+	switch {
+	case k.privateKey != nil:
+		return len(k.privateKey)
+	case k.publicKey != nil:
+		return len(k.publicKey)
+	default:
+		return len(k.secret)
+	}
+}
+
+// Sign returns the versioned signature of a message, e.g. v1,<base64 signature>.
+func (k *Key) Sign(msgID string, timestamp time.Time, payload []byte) (string, error) {
+	content := signedContent(msgID, timestamp, payload)
+	if k.Asymmetric() {
+		if k.privateKey == nil {
+			return "", errors.New("public keys can't sign, a whsk_ secret key is required")
+		}
+		sig := ed25519.Sign(k.privateKey, content)
+		return asymmetricVersion + "," + base64.StdEncoding.EncodeToString(sig), nil
+	}
+	h := hmac.New(sha256.New, k.secret)
+	h.Write(content)
+	return symmetricVersion + "," + base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Verify checks if any of the space separated signatures in header is a valid
+// signature of the message. Signatures of other versions are ignored.
+func (k *Key) Verify(msgID string, timestamp time.Time, payload []byte, header string) error {
+	for _, versioned := range strings.Split(header, " ") {
+		if k.VerifySignature(msgID, timestamp, payload, versioned) {
+			return nil
+		}
+	}
+	return ErrNoMatchingSignature
+}
+
+// VerifySignature checks a single versioned signature, e.g. v1,<base64 signature>.
+func (k *Key) VerifySignature(msgID string, timestamp time.Time, payload []byte, versioned string) bool {
+	parts := strings.SplitN(versioned, ",", 2)
+	if len(parts) != 2 || parts[0] != k.Version() {
+		return false
+	}
+	if k.Asymmetric() {
+		sig, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+		return ed25519.Verify(k.publicKey, signedContent(msgID, timestamp, payload), sig)
+	}
+	expected, _ := k.Sign(msgID, timestamp, payload)
+	return hmac.Equal([]byte(versioned), []byte(expected))
+}
+
+// Headers returns the id, timestamp and signature headers of a signed message,
+// named with prefix (SvixHeaderPrefix or StandardHeaderPrefix).
+func (k *Key) Headers(prefix string, msgID string, timestamp time.Time, payload []byte) (map[string]string, error) {
+	sig, err := k.Sign(msgID, timestamp, payload)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{
+		prefix + "id":        msgID,
+		prefix + "timestamp": strconv.FormatInt(timestamp.Unix(), 10),
+		prefix + "signature": sig,
+	}, nil
+}
+
+// ParseTimestamp parses a timestamp header, in unix seconds.
+func ParseTimestamp(s string) (time.Time, error) {
+	unix, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, ErrInvalidTimestamp
+	}
+	return time.Unix(unix, 0), nil
+}
+
+// CheckTimestamp checks a message's timestamp is within tolerance of now.
+func CheckTimestamp(timestamp time.Time, now time.Time, tolerance time.Duration) error {
+	if now.Sub(timestamp) > tolerance {
+		return ErrMessageTooOld
+	}
+	if timestamp.Sub(now) > tolerance {
+		return ErrMessageTooNew
+	}
+	return nil
+}
+
+func signedContent(msgID string, timestamp time.Time, payload []byte) []byte {
+	return []byte(fmt.Sprintf("%s.%d.%s", msgID, timestamp.Unix(), payload))
+}
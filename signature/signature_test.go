@@ -0,0 +1,219 @@
+package signature
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The symmetric vector is the one from the Standard Webhooks spec and the svix
+// libraries. The asymmetric one signs the same message with the RFC 8032 test 1
+// key, checked against openssl's ed25519.
+const (
+	testMsgID     = "msg_p5jXN8AQM9LWM0D4loKWxJek"
+	testTimestamp = 1614265330
+	testPayload   = `{"test": 2432232314}`
+
+	testSecret        = "whsec_MfKQ9r8GKYqrTwjUPD8ILPZIo2LaLaSw"
+	testSignature     = "v1,g0hM9SsE+OTPJTGt/tmIKtSyZlE3uFJELVlNIOLJ1OE="
+	testPublicKey     = "whpk_11qYAYKxCrfVS/7TyWQHOg7hcvPapiMlrwIaaPcHURo="
+	testSeedKey       = "whsk_nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A="
+	testSecretKey     = "whsk_nWGxne/9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2DXWpgBgrEKt9VL/tPJZAc6DuFy89qmIyWvAhpo9wdRGg=="
+	testAsymmetricSig = "v1a,fldxM4gAKugP6nnt1hdz3sgGfZ6d99nzrMFnZOELIxbzEHoVmAb2ADpkJK7zgPePmPsle0zV9jSeGlHFG2NVAw=="
+)
+
+func mustParseKey(t *testing.T, s string) *Key {
+	t.Helper()
+	key, err := ParseKey(s)
+	if err != nil {
+		t.Fatalf("ParseKey(%q): %s", s, err)
+	}
+	return key
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		key        string
+		asymmetric bool
+		canSign    bool
+		version    string
+		size       int
+		wantErr    bool
+	}{
+		{key: testSecret, canSign: true, version: "v1", size: 24},
+		{key: strings.TrimPrefix(testSecret, SymmetricPrefix), canSign: true, version: "v1", size: 24},
+		{key: testPublicKey, asymmetric: true, version: "v1a", size: 32},
+		{key: testSeedKey, asymmetric: true, canSign: true, version: "v1a", size: 64},
+		{key: testSecretKey, asymmetric: true, canSign: true, version: "v1a", size: 64},
+		{key: "whsec_not base64!", wantErr: true},
+		{key: "whpk_AAAA", wantErr: true},
+		{key: "whpk_not base64!", wantErr: true},
+		{key: "whsk_AAAA", wantErr: true},
+	}
+	for _, tt := range tests {
+		key, err := ParseKey(tt.key)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseKey(%q) succeeded, want an error", tt.key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKey(%q): %s", tt.key, err)
+			continue
+		}
+		if key.Asymmetric() != tt.asymmetric || key.CanSign() != tt.canSign || key.Version() != tt.version || key.Size() != tt.size {
+			t.Errorf("ParseKey(%q) = asymmetric %v, can sign %v, version %s, size %d, want %v, %v, %s, %d",
+				tt.key, key.Asymmetric(), key.CanSign(), key.Version(), key.Size(), tt.asymmetric, tt.canSign, tt.version, tt.size)
+		}
+	}
+}
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: testSecret, want: testSignature},
+		{key: testSeedKey, want: testAsymmetricSig},
+		{key: testSecretKey, want: testAsymmetricSig},
+	}
+	for _, tt := range tests {
+		got, err := mustParseKey(t, tt.key).Sign(testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload))
+		if err != nil {
+			t.Errorf("Sign with %s: %s", tt.key, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Sign with %s = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+
+	if _, err := mustParseKey(t, testPublicKey).Sign(testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload)); err == nil {
+		t.Error("signed with a public key, want an error")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	otherSig := "v1,Ceo5qEr07ixe2NLpvHk3FH9bwy/WavXrAFQ/9tdO6mc="
+	tests := []struct {
+		name    string
+		key     string
+		payload string
+		header  string
+		wantErr bool
+	}{
+		{name: "v1", key: testSecret, payload: testPayload, header: testSignature},
+		{name: "v1 without prefix", key: strings.TrimPrefix(testSecret, SymmetricPrefix), payload: testPayload, header: testSignature},
+		{name: "v1a with public key", key: testPublicKey, payload: testPayload, header: testAsymmetricSig},
+		{name: "v1a with secret key", key: testSecretKey, payload: testPayload, header: testAsymmetricSig},
+		{name: "multiple signatures", key: testSecret, payload: testPayload, header: otherSig + " " + testSignature},
+		{name: "multiple versions", key: testPublicKey, payload: testPayload, header: testSignature + " " + testAsymmetricSig},
+		{name: "v1 key ignores v1a", key: testSecret, payload: testPayload, header: "v1a," + strings.TrimPrefix(testSignature, "v1,"), wantErr: true},
+		{name: "v1a key ignores v1", key: testPublicKey, payload: testPayload, header: "v1," + strings.TrimPrefix(testAsymmetricSig, "v1a,"), wantErr: true},
+		{name: "modified payload", key: testSecret, payload: `{"test": 2432232315}`, header: testSignature, wantErr: true},
+		{name: "modified asymmetric payload", key: testPublicKey, payload: `{"test": 2432232315}`, header: testAsymmetricSig, wantErr: true},
+		{name: "other signature", key: testSecret, payload: testPayload, header: otherSig, wantErr: true},
+		{name: "no version", key: testSecret, payload: testPayload, header: strings.TrimPrefix(testSignature, "v1,"), wantErr: true},
+		{name: "invalid base64", key: testPublicKey, payload: testPayload, header: "v1a,not base64!", wantErr: true},
+		{name: "empty header", key: testSecret, payload: testPayload, header: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := mustParseKey(t, tt.key).Verify(testMsgID, time.Unix(testTimestamp, 0), []byte(tt.payload), tt.header)
+		if tt.wantErr {
+			if err != ErrNoMatchingSignature {
+				t.Errorf("%s: err = %v, want %v", tt.name, err, ErrNoMatchingSignature)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		}
+	}
+
+	// the signed content includes the id and timestamp
+	key := mustParseKey(t, testSecret)
+	if key.Verify("msg_other", time.Unix(testTimestamp, 0), []byte(testPayload), testSignature) == nil {
+		t.Error("verified with another message id")
+	}
+	if key.Verify(testMsgID, time.Unix(testTimestamp+1, 0), []byte(testPayload), testSignature) == nil {
+		t.Error("verified with another timestamp")
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	for _, prefix := range []string{SvixHeaderPrefix, StandardHeaderPrefix} {
+		headers, err := mustParseKey(t, testSecret).Headers(prefix, testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if headers[prefix+"id"] != testMsgID || headers[prefix+"timestamp"] != "1614265330" || headers[prefix+"signature"] != testSignature {
+			t.Errorf("Headers(%s) = %v", prefix, headers)
+		}
+	}
+}
+
+func TestCheckTimestamp(t *testing.T) {
+	now := time.Unix(testTimestamp, 0)
+	tests := []struct {
+		timestamp time.Time
+		tolerance time.Duration
+		want      error
+	}{
+		{timestamp: now, tolerance: DefaultTolerance},
+		{timestamp: now.Add(-DefaultTolerance), tolerance: DefaultTolerance},
+		{timestamp: now.Add(DefaultTolerance), tolerance: DefaultTolerance},
+		{timestamp: now.Add(-DefaultTolerance - time.Second), tolerance: DefaultTolerance, want: ErrMessageTooOld},
+		{timestamp: now.Add(DefaultTolerance + time.Second), tolerance: DefaultTolerance, want: ErrMessageTooNew},
+		{timestamp: now.Add(-time.Hour), tolerance: 2 * time.Hour},
+		{timestamp: now.Add(time.Second), tolerance: 0, want: ErrMessageTooNew},
+	}
+	for _, tt := range tests {
+		if err := CheckTimestamp(tt.timestamp, now, tt.tolerance); err != tt.want {
+			t.Errorf("CheckTimestamp(now%+v, tolerance %v) = %v, want %v", tt.timestamp.Sub(now), tt.tolerance, err, tt.want)
+		}
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	ts, err := ParseTimestamp("1614265330")
+	if err != nil || !ts.Equal(time.Unix(testTimestamp, 0)) {
+		t.Errorf("ParseTimestamp = %v, %v", ts, err)
+	}
+	for _, s := range []string{"", "abc", "1614265330.5", "2021-02-25T15:02:10Z"} {
+		if _, err := ParseTimestamp(s); err != ErrInvalidTimestamp {
+			t.Errorf("ParseTimestamp(%q) err = %v, want %v", s, err, ErrInvalidTimestamp)
+		}
+	}
+}
+
+func TestGenerateKeyPair(t *testing.T) {
+	publicKey, secretKey, err := GenerateKeyPair()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(publicKey, PublicKeyPrefix) || !strings.HasPrefix(secretKey, SecretKeyPrefix) {
+		t.Fatalf("GenerateKeyPair = %s, %s, want whpk_ and whsk_ keys", publicKey, secretKey)
+	}
+	sig, err := mustParseKey(t, secretKey).Sign(testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mustParseKey(t, publicKey).Verify(testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload), sig); err != nil {
+		t.Errorf("public key doesn't verify the secret key's signature: %s", err)
+	}
+	if other, _, _ := GenerateKeyPair(); mustParseKey(t, other).Verify(testMsgID, time.Unix(testTimestamp, 0), []byte(testPayload), sig) == nil {
+		t.Error("another public key verified the signature")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := mustParseKey(t, secret)
+	if !strings.HasPrefix(secret, SymmetricPrefix) || key.Asymmetric() || key.Size() != 24 {
+		t.Errorf("GenerateSecret = %s, want a 24 byte whsec_ secret", secret)
+	}
+}
@@ -0,0 +1,425 @@
+package tui
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/chzyer/readline"
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/relay"
+)
+
+const (
+	maxLogLines    = 200
+	logPaneHeight  = 5
+	resizeInterval = 500 * time.Millisecond
+	defaultWidth   = 80
+	defaultHeight  = 24
+)
+
+var ansiPattern = regexp.MustCompile("\x1b\\[[0-9;?]*[A-Za-z]")
+
+// ReplayFunc sends a recorded request to the local server again.
+type ReplayFunc func(rec *relay.Record) (*http.Response, error)
+
+// Inspector is a full screen terminal UI listing relayed requests,
+// with a detail pane showing the headers and body of the selected request.
+type Inspector struct {
+	mu         sync.Mutex
+	printer    *pretty.Printer
+	receiveURL string
+	records    []*relay.Record
+	logs       []string
+	partialLog string
+	selected   int
+	scroll     int
+	follow     bool
+
+	redraw chan struct{}
+}
+
+func NewInspector(printer *pretty.Printer) *Inspector {
+	return &Inspector{
+		printer: printer,
+		follow:  true,
+		redraw:  make(chan struct{}, 1),
+	}
+}
+
+// Add adds a handled request to the list, it is safe for concurrent use.
+func (i *Inspector) Add(rec *relay.Record) {
+	i.mu.Lock()
+	i.records = append(i.records, rec)
+	if i.follow {
+		i.selected = len(i.records) - 1
+		i.scroll = 0
+	}
+	i.mu.Unlock()
+	i.requestRedraw()
+}
+
+func (i *Inspector) SetReceiveURL(url string) {
+	i.mu.Lock()
+	i.receiveURL = url
+	i.mu.Unlock()
+	i.requestRedraw()
+}
+
+// Write adds output to the log pane, allowing the inspector to be used as the relay's output.
+func (i *Inspector) Write(b []byte) (int, error) {
+	i.mu.Lock()
+	lines := strings.Split(i.partialLog+ansiPattern.ReplaceAllString(string(b), ""), "\n")
+	i.partialLog = lines[len(lines)-1]
+	for _, line := range lines[:len(lines)-1] {
+		if strings.TrimSpace(line) != "" {
+			i.logs = append(i.logs, line)
+		}
+	}
+	if len(i.logs) > maxLogLines {
+		i.logs = i.logs[len(i.logs)-maxLogLines:]
+	}
+	i.mu.Unlock()
+	i.requestRedraw()
+	return len(b), nil
+}
+
+func (i *Inspector) requestRedraw() {
+	select {
+	case i.redraw <- struct{}{}:
+	default:
+	}
+}
+
+// Run takes over the terminal until the user quits.
+func (i *Inspector) Run(replay ReplayFunc) error {
+	fd := int(os.Stdin.Fd())
+	if !readline.IsTerminal(fd) {
+		return fmt.Errorf("the inspector requires an interactive terminal")
+	}
+	state, err := readline.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = readline.Restore(fd, state)
+	}()
+
+	fmt.Print(enterAltScreen + hideCursor)
+	defer fmt.Print(showCursor + exitAltScreen)
+
+	keys := make(chan key, 10)
+	go readKeys(keys)
+
+	ticker := time.NewTicker(resizeInterval)
+	defer ticker.Stop()
+
+	width, height := terminalSize(fd)
+	i.render(width, height)
+	for {
+		select {
+		case <-i.redraw:
+			i.render(width, height)
+		case <-ticker.C:
+			newWidth, newHeight := terminalSize(fd)
+			if newWidth != width || newHeight != height {
+				width, height = newWidth, newHeight
+				i.render(width, height)
+			}
+		case k := <-keys:
+			if k == keyQuit {
+				return nil
+			}
+			i.handleKey(k, replay, height)
+			i.render(width, height)
+		}
+	}
+}
+
+func (i *Inspector) handleKey(k key, replay ReplayFunc, height int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	pageSize := height / 3
+	switch k {
+	case keyUp:
+		if i.selected > 0 {
+			i.selected--
+			i.scroll = 0
+		}
+		i.follow = false
+	case keyDown:
+		if i.selected < len(i.records)-1 {
+			i.selected++
+			i.scroll = 0
+		}
+		i.follow = i.selected == len(i.records)-1
+	case keyFirst:
+		i.selected = 0
+		i.scroll = 0
+		i.follow = false
+	case keyLast:
+		i.selected = len(i.records) - 1
+		i.scroll = 0
+		i.follow = true
+	case keyPageUp:
+		i.scroll -= pageSize
+		if i.scroll < 0 {
+			i.scroll = 0
+		}
+	case keyPageDown:
+		i.scroll += pageSize
+	case keyReplay:
+		if len(i.records) == 0 {
+			return
+		}
+		rec := i.records[i.selected]
+		index := i.selected + 1
+		go func() {
+			start := time.Now()
+			res, err := replay(rec)
+			if err != nil {
+				fmt.Fprintf(i, "Replay of #%d failed: %s\n", index, err.Error())
+				return
+			}
+			res.Body.Close()
+			fmt.Fprintf(i, "Replay of #%d received \"%s\" (%dms)\n", index, res.Status, time.Since(start).Milliseconds())
+		}()
+	}
+}
+
+func (i *Inspector) render(width int, height int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	// title, column headers, two dividers and the footer
+	chromeHeight := 5
+	listHeight := (height - chromeHeight - logPaneHeight) * 2 / 5
+	if listHeight < 3 {
+		listHeight = 3
+	}
+	detailHeight := height - chromeHeight - logPaneHeight - listHeight
+	if detailHeight < 0 {
+		detailHeight = 0
+	}
+
+	var lines []string
+	receiveURL := i.receiveURL
+	if receiveURL == "" {
+		receiveURL = "connecting..."
+	}
+	lines = append(lines, inverse+pad(fmt.Sprintf(" svix listen | %s | %d requests", receiveURL, len(i.records)), width)+reset)
+	lines = append(lines, bold+fmt.Sprintf("  %-5s %-6s %-8s %-7s %-30s %s", "#", "STATUS", "LATENCY", "METHOD", "EVENT TYPE", "RECEIVED")+reset)
+
+	// keep the selected request visible
+	first := 0
+	if i.selected >= listHeight {
+		first = i.selected - listHeight + 1
+	}
+	for row := 0; row < listHeight; row++ {
+		index := first + row
+		if index >= len(i.records) {
+			lines = append(lines, "")
+			continue
+		}
+		line := i.formatListRow(index)
+		if index == i.selected {
+			line = inverse + pad(ansiPattern.ReplaceAllString(line, ""), width) + reset
+		}
+		lines = append(lines, line)
+	}
+
+	var detail []string
+	if len(i.records) > 0 {
+		rec := i.records[i.selected]
+		lines = append(lines, divider(fmt.Sprintf("Request #%d %s", i.selected+1, rec.ID), width))
+		detail = i.formatDetail(rec)
+	} else {
+		lines = append(lines, divider("Request", width))
+		detail = []string{"Waiting for requests..."}
+	}
+	if i.scroll > len(detail)-1 {
+		i.scroll = len(detail) - 1
+	}
+	if i.scroll < 0 {
+		i.scroll = 0
+	}
+	detail = detail[i.scroll:]
+	for row := 0; row < detailHeight; row++ {
+		if row < len(detail) {
+			lines = append(lines, detail[row])
+		} else {
+			lines = append(lines, "")
+		}
+	}
+
+	lines = append(lines, divider("Log", width))
+	logs := i.logs
+	if len(logs) > logPaneHeight {
+		logs = logs[len(logs)-logPaneHeight:]
+	}
+	for row := 0; row < logPaneHeight; row++ {
+		if row < len(logs) {
+			lines = append(lines, dim+logs[row]+reset)
+		} else {
+			lines = append(lines, "")
+		}
+	}
+	lines = append(lines, inverse+pad(" up/down select | pgup/pgdn scroll | g/G first/last | r replay | q quit", width)+reset)
+
+	var buf strings.Builder
+	buf.WriteString(cursorHome)
+	for n, line := range lines {
+		if n >= height {
+			break
+		}
+		buf.WriteString(truncate(line, width))
+		buf.WriteString(clearLine)
+		if n < height-1 && n < len(lines)-1 {
+			buf.WriteString("\r\n")
+		}
+	}
+	buf.WriteString(clearScreenDown)
+	fmt.Print(buf.String())
+}
+
+func (i *Inspector) formatListRow(index int) string {
+	rec := i.records[index]
+	status := red + "ERR" + reset
+	latency := "-"
+	if rec.Response != nil {
+		status = statusColor(rec.Response.Status) + fmt.Sprintf("%d", rec.Response.Status) + reset
+		latency = fmt.Sprintf("%dms", rec.Response.LatencyMs)
+	}
+	eventType := rec.EventType()
+	if eventType == "" {
+		eventType = "-"
+	}
+	// pad the status manually, the color codes would throw off the width
+	return fmt.Sprintf("  %-5d %s%s %-8s %-7s %-30s %s",
+		index+1,
+		status,
+		strings.Repeat(" ", 6-len(ansiPattern.ReplaceAllString(status, ""))),
+		latency,
+		escapeControl(rec.Method),
+		escapeControl(eventType),
+		rec.Timestamp.Local().Format("15:04:05"),
+	)
+}
+
+func (i *Inspector) formatDetail(rec *relay.Record) []string {
+	var lines []string
+	path := rec.Path
+	if path == "" {
+		path = "/"
+	}
+	summary := fmt.Sprintf("%s %s  received %s", escapeControl(rec.Method), escapeControl(path), rec.Timestamp.Local().Format("2006-01-02 15:04:05.000"))
+	if rec.Verified != nil {
+		if *rec.Verified {
+			summary += "  " + green + "signature valid" + reset
+		} else {
+			summary += "  " + red + "signature invalid" + reset
+		}
+	}
+	lines = append(lines, summary, "")
+
+	lines = append(lines, bold+"Request Headers"+reset)
+	lines = append(lines, formatHeaders(rec.Headers)...)
+	lines = append(lines, "", bold+"Request Body"+reset)
+	lines = append(lines, i.formatBody(string(rec.Body))...)
+
+	if rec.Error != "" {
+		lines = append(lines, "", red+"Error: "+escapeControl(rec.Error)+reset)
+	}
+	if rec.Response != nil {
+		lines = append(lines, "", bold+fmt.Sprintf("Response %s%d %s%s (%dms)",
+			statusColor(rec.Response.Status),
+			rec.Response.Status,
+			http.StatusText(rec.Response.Status),
+			reset+bold,
+			rec.Response.LatencyMs,
+		)+reset)
+		lines = append(lines, formatHeaders(rec.Response.Headers)...)
+		lines = append(lines, "", bold+"Response Body"+reset)
+		lines = append(lines, i.formatBody(rec.Response.Body)...)
+	}
+	return lines
+}
+
+func (i *Inspector) formatBody(body string) []string {
+	if body == "" {
+		return []string{dim + "(empty)" + reset}
+	}
+	if !json.Valid([]byte(body)) {
+		// anything but json is shown as is, so escape what would mess with the screen
+		lines := strings.Split(strings.TrimRight(body, "\n"), "\n")
+		for n, line := range lines {
+			lines[n] = escapeControl(strings.ReplaceAll(line, "\t", "  "))
+		}
+		return lines
+	}
+	formatted := strings.TrimRight(string(i.printer.Format([]byte(body))), "\n")
+	return strings.Split(strings.ReplaceAll(formatted, "\t", "  "), "\n")
+}
+
+func formatHeaders(headers map[string]string) []string {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := make([]string, 0, len(names))
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("  %s%s:%s %s", cyan, escapeControl(name), reset, escapeControl(headers[name])))
+	}
+	return lines
+}
+
+// escapeControl quotes control characters, other non printable characters and
+// invalid utf-8 in s, so they can't move the cursor or change colors when drawn.
+func escapeControl(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		r, size := utf8.DecodeRuneInString(s)
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, "\\x%02x", s[0])
+		case unicode.IsPrint(r) || r == ' ':
+			b.WriteString(s[:size])
+		default:
+			quoted := strconv.QuoteRune(r)
+			b.WriteString(quoted[1 : len(quoted)-1])
+		}
+		s = s[size:]
+	}
+	return b.String()
+}
+
+func statusColor(status int) string {
+	switch {
+	case status >= 200 && status < 300:
+		return green
+	case status >= 300 && status < 400:
+		return yellow
+	default:
+		return red
+	}
+}
+
+func terminalSize(fd int) (int, int) {
+	width, height, err := readline.GetSize(fd)
+	if err != nil || width <= 0 || height <= 0 {
+		return defaultWidth, defaultHeight
+	}
+	return width, height
+}
@@ -0,0 +1,57 @@
+package tui
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/svix/svix-cli/pretty"
+	"github.com/svix/svix-cli/relay"
+)
+
+func TestEscapeControl(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "application/json", want: "application/json"},
+		{s: "héllo wörld ✓", want: "héllo wörld ✓"},
+		{s: "\x1b[2J\x1b[Hgotcha", want: `\x1b[2J\x1b[Hgotcha`},
+		{s: "a\rb\x00c\x7f", want: `a\rb\x00c\x7f`},
+		{s: "zero\u200bwidth", want: `zero\u200bwidth`},
+		{s: "bad \xff\xfe utf-8", want: `bad \xff\xfe utf-8`},
+	}
+	for _, tt := range tests {
+		if got := escapeControl(tt.s); got != tt.want {
+			t.Errorf("escapeControl(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDetailEscapes(t *testing.T) {
+	i := NewInspector(pretty.NewPrinter(&pretty.PrinterOptions{}))
+	rec := &relay.Record{
+		Method:  "POST",
+		Path:    "/hooks\x1b]0;title\x07",
+		Headers: map[string]string{"X-Evil\x1b[31m": "value\x1b[2J"},
+		Body:    []byte("line one\n\x1b[2Jline two\twith tab\n\xff"),
+		Response: &relay.RecordResponse{
+			Status:  200,
+			Headers: map[string]string{},
+			Body:    `{"message": "ok \u001b"}`,
+		},
+	}
+	for _, line := range i.formatDetail(rec) {
+		plain := ansiPattern.ReplaceAllString(line, "")
+		for _, r := range plain {
+			if r < ' ' || r == 0x7f {
+				t.Errorf("line %q has control character %q", line, r)
+			}
+		}
+	}
+	detail := strings.Join(i.formatDetail(rec), "\n")
+	for _, want := range []string{`/hooks\x1b]0;title\a`, `X-Evil\x1b[31m`, `value\x1b[2J`, `\x1b[2Jline two  with tab`, `\xff`, `\u001b`} {
+		if !strings.Contains(detail, want) {
+			t.Errorf("detail doesn't contain %q:\n%s", want, detail)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	enterAltScreen  = "\x1b[?1049h"
+	exitAltScreen   = "\x1b[?1049l"
+	hideCursor      = "\x1b[?25l"
+	showCursor      = "\x1b[?25h"
+	cursorHome      = "\x1b[H"
+	clearLine       = "\x1b[K"
+	clearScreenDown = "\x1b[J"
+
+	reset   = "\x1b[0m"
+	bold    = "\x1b[1m"
+	dim     = "\x1b[2m"
+	inverse = "\x1b[7m"
+	red     = "\x1b[31m"
+	green   = "\x1b[32m"
+	yellow  = "\x1b[33m"
+	cyan    = "\x1b[36m"
+)
+
+type key int
+
+const (
+	keyUnknown key = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyFirst
+	keyLast
+	keyReplay
+	keyQuit
+)
+
+// readKeys reads key presses from stdin, which must be in raw mode.
+func readKeys(keys chan<- key) {
+	buf := make([]byte, 32)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			keys <- keyQuit
+			return
+		}
+		if k := parseKey(string(buf[:n])); k != keyUnknown {
+			keys <- k
+		}
+	}
+}
+
+func parseKey(in string) key {
+	switch in {
+	case "\x1b[A", "\x1bOA", "k":
+		return keyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return keyDown
+	case "\x1b[5~", "b":
+		return keyPageUp
+	case "\x1b[6~", " ":
+		return keyPageDown
+	case "\x1b[H", "\x1b[1~", "g":
+		return keyFirst
+	case "\x1b[F", "\x1b[4~", "G":
+		return keyLast
+	case "r":
+		return keyReplay
+	case "q", "\x03", "\x04":
+		return keyQuit
+	}
+	return keyUnknown
+}
+
+// truncate cuts s to width visible characters, ignoring ansi escape codes.
+func truncate(s string, width int) string {
+	var b strings.Builder
+	visible := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			if loc := ansiPattern.FindStringIndex(s[i:]); loc != nil && loc[0] == 0 {
+				b.WriteString(s[i : i+loc[1]])
+				i += loc[1]
+				continue
+			}
+		}
+		if visible >= width {
+			b.WriteString(reset)
+			break
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteRune(r)
+		visible++
+		i += size
+	}
+	return b.String()
+}
+
+// pad fills s with spaces up to width visible characters.
+func pad(s string, width int) string {
+	visible := utf8.RuneCountInString(ansiPattern.ReplaceAllString(s, ""))
+	if visible >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-visible)
+}
+
+func divider(title string, width int) string {
+	line := "-- " + title + " "
+	if n := width - utf8.RuneCountInString(line); n > 0 {
+		line += strings.Repeat("-", n)
+	}
+	return dim + line + reset
+}
@@ -0,0 +1,27 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const idAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// NewID generates an id in the format used by Svix, e.g. msg_2FVDJqMlQvAgcKtGQ8Bnp8VcVK7.
+func NewID(prefix string) string {
+	return prefix + "_" + RandomString(27)
+}
+
+// RandomString generates a random alphanumeric string of length n.
+func RandomString(n int) string {
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(idAlphabet)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			panic(err)
+		}
+		b[i] = idAlphabet[idx.Int64()]
+	}
+	return string(b)
+}